@@ -0,0 +1,332 @@
+// Package blaisetest provides a fake blaise server for running pascal against
+// canned data without a real blaise instance.
+package blaisetest
+
+import (
+	"cmp"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vincbro/pascal/blaise"
+)
+
+//go:embed fixtures/*.json
+var fixtures embed.FS
+
+// Server is a fake blaise server built on httptest. Itineraries are matched
+// on their from and to IDs only, the requested time is ignored.
+type Server struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	itineraries map[string][]blaise.Itinerary
+	areas       []blaise.Location
+	departures  []blaise.Departure
+	age         uint32
+	refreshes   []string
+	latency     time.Duration
+	failures    map[string][]int
+	malformed   map[string]bool
+	loading     bool
+}
+
+// NewServer starts a fake blaise server loaded with the bundled fixtures.
+// Close it when done.
+func NewServer() *Server {
+	s := &Server{
+		itineraries: make(map[string][]blaise.Itinerary),
+		areas:       make([]blaise.Location, 0),
+		failures:    make(map[string][]int),
+		malformed:   make(map[string]bool),
+	}
+	if err := s.load(fixtures, "fixtures"); err != nil {
+		panic(fmt.Sprintf("blaisetest: bundled fixtures: %v", err))
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /routing", s.routing)
+	mux.HandleFunc("GET /search/area", s.searchArea)
+	mux.HandleFunc("GET /departures", s.departuresFrom)
+	mux.HandleFunc("GET /gtfs/age", s.gtfsAge)
+	mux.HandleFunc("GET /gtfs/fetch-url", s.fetchURL)
+	s.Server = httptest.NewServer(s.middleware(mux))
+	return s
+}
+
+// Client returns a blaise client talking to the fake server.
+func (s *Server) Client() *blaise.Client {
+	return blaise.NewClient(s.URL)
+}
+
+// LoadFixtures adds the itineraries.json, areas.json and departures.json found
+// in dir, any of them may be missing.
+func (s *Server) LoadFixtures(dir string) error {
+	return s.load(os.DirFS(dir), ".")
+}
+
+func (s *Server) load(fsys fs.FS, dir string) error {
+	var itineraries []blaise.Itinerary
+	if err := readFixture(fsys, path.Join(dir, "itineraries.json"), &itineraries); err != nil {
+		return err
+	}
+	var areas []blaise.Location
+	if err := readFixture(fsys, path.Join(dir, "areas.json"), &areas); err != nil {
+		return err
+	}
+	var departures []blaise.Departure
+	if err := readFixture(fsys, path.Join(dir, "departures.json"), &departures); err != nil {
+		return err
+	}
+
+	for _, itinerary := range itineraries {
+		s.AddItinerary(itinerary)
+	}
+	s.mu.Lock()
+	s.areas = append(s.areas, areas...)
+	s.departures = append(s.departures, departures...)
+	s.mu.Unlock()
+	return nil
+}
+
+func readFixture(fsys fs.FS, name string, v any) error {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	return nil
+}
+
+func routeKey(from, to string) string {
+	return from + ">" + to
+}
+
+// AddItinerary serves itinerary for routing requests between its endpoints.
+// Itineraries added for the same endpoints are alternatives, best first.
+func (s *Server) AddItinerary(itinerary blaise.Itinerary) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := routeKey(itinerary.From.ID, itinerary.To.ID)
+	s.itineraries[key] = append(s.itineraries[key], itinerary)
+}
+
+// SetAge sets the GTFS data age in seconds reported by /gtfs/age.
+func (s *Server) SetAge(age uint32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.age = age
+}
+
+// SetLatency delays every response by d.
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// Fail makes the next requests to path respond with the given status codes,
+// one per request, before going back to normal.
+func (s *Server) Fail(path string, statuses ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[path] = append(s.failures[path], statuses...)
+}
+
+// SetMalformed makes every response on path an undecodable body.
+func (s *Server) SetMalformed(path string, malformed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.malformed[path] = malformed
+}
+
+// SetLoading makes the server answer like blaise does while it (re)loads its
+// GTFS data.
+func (s *Server) SetLoading(loading bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loading = loading
+}
+
+// Refreshes returns the GTFS urls of every refresh that has been triggered.
+func (s *Server) Refreshes() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.refreshes...)
+}
+
+func (s *Server) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		latency := s.latency
+		loading := s.loading
+		malformed := s.malformed[r.URL.Path]
+		status := 0
+		if pending := s.failures[r.URL.Path]; len(pending) > 0 {
+			status = pending[0]
+			s.failures[r.URL.Path] = pending[1:]
+		}
+		s.mu.Unlock()
+
+		if latency > 0 {
+			select {
+			case <-time.After(latency):
+			case <-r.Context().Done():
+				return
+			}
+		}
+		if status != 0 {
+			writeError(w, status, http.StatusText(status))
+			return
+		}
+		if loading && r.URL.Path != "/gtfs/fetch-url" {
+			writeError(w, http.StatusServiceUnavailable, "gtfs data is loading")
+			return
+		}
+		if malformed {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"departure_time": "not a time", "legs": [`)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+func (s *Server) knownArea(id string) bool {
+	for _, area := range s.areas {
+		if area.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) routing(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("departure_at") == "" && q.Get("arrive_at") == "" {
+		writeError(w, http.StatusBadRequest, "missing departure_at or arrive_at")
+		return
+	}
+
+	s.mu.Lock()
+	known := s.knownArea(q.Get("from")) && s.knownArea(q.Get("to"))
+	itineraries := s.itineraries[routeKey(q.Get("from"), q.Get("to"))]
+	s.mu.Unlock()
+	if !known {
+		writeError(w, http.StatusNotFound, "unknown location")
+		return
+	}
+	if len(itineraries) == 0 {
+		writeError(w, http.StatusNotFound, "no route found")
+		return
+	}
+
+	// Without count answer like older blaise versions, with a single itinerary
+	if !q.Has("count") {
+		writeJSON(w, itineraries[0])
+		return
+	}
+	count, err := strconv.Atoi(q.Get("count"))
+	if err != nil || count <= 0 {
+		writeError(w, http.StatusBadRequest, "invalid count")
+		return
+	}
+	writeJSON(w, itineraries[:min(count, len(itineraries))])
+}
+
+func (s *Server) searchArea(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	query := strings.ToLower(q.Get("q"))
+	count, err := strconv.Atoi(q.Get("count"))
+	if err != nil || count <= 0 {
+		count = 10
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	areas := make([]blaise.Location, 0, count)
+	for _, area := range s.areas {
+		if len(areas) == count {
+			break
+		}
+		if strings.Contains(strings.ToLower(area.Name), query) {
+			areas = append(areas, area)
+		}
+	}
+	writeJSON(w, areas)
+}
+
+// departuresFrom answers with the departures from the stop at or after the
+// requested time, in the order they leave.
+func (s *Server) departuresFrom(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var h, m, sec int
+	if _, err := fmt.Sscanf(q.Get("at"), "%d:%d:%d", &h, &m, &sec); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid at")
+		return
+	}
+	at := blaise.Time(h*3600 + m*60 + sec)
+	count, err := strconv.Atoi(q.Get("count"))
+	if err != nil || count <= 0 {
+		count = 10
+	}
+
+	s.mu.Lock()
+	known := s.knownArea(q.Get("from"))
+	departures := make([]blaise.Departure, 0, count)
+	for _, departure := range s.departures {
+		if departure.Stop.ID == q.Get("from") && departure.DepartureTime >= at {
+			departures = append(departures, departure)
+		}
+	}
+	s.mu.Unlock()
+	if !known {
+		writeError(w, http.StatusNotFound, "unknown stop")
+		return
+	}
+	slices.SortStableFunc(departures, func(a, b blaise.Departure) int {
+		return cmp.Compare(a.DepartureTime, b.DepartureTime)
+	})
+	writeJSON(w, departures[:min(count, len(departures))])
+}
+
+func (s *Server) gtfsAge(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprintf(w, "%d", s.age)
+}
+
+func (s *Server) fetchURL(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refreshes = append(s.refreshes, r.URL.Query().Get("q"))
+	s.age = 0
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -8,13 +8,15 @@ import (
 type Client struct {
 	BaseURL    string
 	HTTPClient *http.Client
+	Policies   map[CallType]Policy
+	Breaker    *CircuitBreaker
 }
 
 func NewClient(baseURL string) *Client {
 	return &Client{
-		BaseURL: baseURL,
-		HTTPClient: &http.Client{
-			Timeout: 2 * time.Second,
-		},
+		BaseURL:    baseURL,
+		HTTPClient: &http.Client{},
+		Policies:   DefaultPolicies(),
+		Breaker:    NewCircuitBreaker(5, 30*time.Second),
 	}
 }
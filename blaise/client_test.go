@@ -0,0 +1,287 @@
+package blaise_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/vincbro/pascal/blaise"
+	"github.com/vincbro/pascal/blaise/blaisetest"
+)
+
+const (
+	odenplan = "740021666"
+	tekniska = "740020749"
+	solna    = "740021705"
+)
+
+// newClient starts a fake server and a client against it that retries
+// without waiting, so failure paths don't slow the tests down.
+func newClient(t *testing.T) (*blaise.Client, *blaisetest.Server) {
+	t.Helper()
+	server := blaisetest.NewServer()
+	t.Cleanup(server.Close)
+	client := server.Client()
+	for call, policy := range client.Policies {
+		policy.BaseDelay = time.Millisecond
+		policy.MaxDelay = time.Millisecond
+		client.Policies[call] = policy
+	}
+	return client, server
+}
+
+func TestRouting(t *testing.T) {
+	client, _ := newClient(t)
+	itineraries, err := client.Routing(context.Background(), odenplan, tekniska, "08:00:00", true, 5)
+	if err != nil {
+		t.Fatalf("routing failed: %v", err)
+	}
+	if len(itineraries) != 2 {
+		t.Fatalf("got %d itineraries, want 2", len(itineraries))
+	}
+	first := itineraries[0]
+	if first.DepartureTime != 28800 || first.ArrivalTime != 29580 || len(first.Legs) != 3 {
+		t.Errorf("first itinerary %d-%d with %d legs, want 28800-29580 with 3", first.DepartureTime, first.ArrivalTime, len(first.Legs))
+	}
+
+	itineraries, err = client.Routing(context.Background(), odenplan, tekniska, "08:00:00", true, 1)
+	if err != nil {
+		t.Fatalf("routing failed: %v", err)
+	}
+	if len(itineraries) != 1 {
+		t.Errorf("got %d itineraries with count 1, want 1", len(itineraries))
+	}
+}
+
+func TestRoutingErrors(t *testing.T) {
+	client, _ := newClient(t)
+	tests := []struct {
+		name     string
+		from, to string
+		want     error
+	}{
+		{"unknown location", "1", tekniska, blaise.ErrUnknownLocation},
+		{"no route", tekniska, solna, blaise.ErrNoRoute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.Routing(context.Background(), tt.from, tt.to, "08:00:00", true, 1)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("got error %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchAreas(t *testing.T) {
+	client, _ := newClient(t)
+	areas, err := client.SearchAreas(context.Background(), "oden", 5)
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if len(areas) != 1 || areas[0].ID != odenplan {
+		t.Errorf("got areas %v, want only Odenplan", areas)
+	}
+}
+
+func TestDepartures(t *testing.T) {
+	client, server := newClient(t)
+	departures, err := client.Departures(context.Background(), odenplan, "08:00:00", 3)
+	if err != nil {
+		t.Fatalf("departures failed: %v", err)
+	}
+	want := []blaise.Time{28800, 29040, 29100}
+	if len(departures) != len(want) {
+		t.Fatalf("got %d departures, want %d", len(departures), len(want))
+	}
+	for i, departure := range departures {
+		if departure.DepartureTime != want[i] || departure.Stop.ID != odenplan {
+			t.Errorf("departure %d leaves %s at %s, want %s", i, departure.Stop.ID, departure.DepartureTime.ToHMSString(), want[i].ToHMSString())
+		}
+	}
+	if departures[0].ShortName == nil || *departures[0].ShortName != "19" || departures[0].TripID == nil {
+		t.Errorf("first departure is %+v, want the 19 with its trip", departures[0])
+	}
+
+	if departures, err := client.Departures(context.Background(), solna, "08:00:00", 3); err != nil || len(departures) != 0 {
+		t.Errorf("got %v, %v from a stop without departures", departures, err)
+	}
+	if _, err := client.Departures(context.Background(), "740099999", "08:00:00", 3); !errors.Is(err, blaise.ErrUnknownLocation) {
+		t.Errorf("got error %v for an unknown stop, want ErrUnknownLocation", err)
+	}
+
+	server.Fail("/departures", http.StatusBadGateway)
+	if _, err := client.Departures(context.Background(), odenplan, "08:00:00", 3); err != nil {
+		t.Errorf("departures failed after one failure: %v", err)
+	}
+}
+
+func TestRetriesFailures(t *testing.T) {
+	client, server := newClient(t)
+	server.Fail("/routing", http.StatusBadGateway, http.StatusServiceUnavailable)
+	if _, err := client.Routing(context.Background(), odenplan, tekniska, "08:00:00", true, 1); err != nil {
+		t.Errorf("routing failed after two failures and three attempts: %v", err)
+	}
+
+	server.Fail("/routing", http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError)
+	_, err := client.Routing(context.Background(), odenplan, tekniska, "08:00:00", true, 1)
+	if !errors.Is(err, blaise.ErrUnavailable) {
+		t.Errorf("got error %v once attempts ran out, want ErrUnavailable", err)
+	}
+}
+
+func TestDoesNotRetryBadRequests(t *testing.T) {
+	client, server := newClient(t)
+	server.Fail("/routing", http.StatusBadRequest)
+	_, err := client.Routing(context.Background(), odenplan, tekniska, "08:00:00", true, 1)
+	if !errors.Is(err, blaise.ErrBadRequest) {
+		t.Errorf("got error %v, want ErrBadRequest", err)
+	}
+}
+
+func TestLatency(t *testing.T) {
+	client, server := newClient(t)
+	client.Policies[blaise.CallRouting] = blaise.Policy{Timeout: 20 * time.Millisecond, MaxAttempts: 2}
+	server.SetLatency(200 * time.Millisecond)
+
+	start := time.Now()
+	_, err := client.Routing(context.Background(), odenplan, tekniska, "08:00:00", true, 1)
+	if !errors.Is(err, blaise.ErrUnavailable) {
+		t.Errorf("got error %v from a slow server, want ErrUnavailable", err)
+	}
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Errorf("routing took %v, the attempt timeout was not applied", elapsed)
+	}
+
+	// Giving up is the caller's choice, not blaise failing
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = client.Routing(ctx, odenplan, tekniska, "08:00:00", true, 1)
+	if errors.Is(err, blaise.ErrUnavailable) || !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v from a canceled call, want context.Canceled", err)
+	}
+}
+
+func TestMalformed(t *testing.T) {
+	client, server := newClient(t)
+	server.SetMalformed("/routing", true)
+	_, err := client.Routing(context.Background(), odenplan, tekniska, "08:00:00", true, 1)
+	if err == nil {
+		t.Fatal("decoded a malformed response")
+	}
+	if errors.Is(err, blaise.ErrUnavailable) {
+		t.Errorf("got error %v, a malformed body is not blaise being down", err)
+	}
+
+	server.SetMalformed("/gtfs/age", true)
+	if _, err := client.GetAge(context.Background()); err == nil {
+		t.Error("parsed a malformed age")
+	}
+}
+
+func TestLoading(t *testing.T) {
+	client, server := newClient(t)
+	server.SetLoading(true)
+	_, err := client.Routing(context.Background(), odenplan, tekniska, "08:00:00", true, 1)
+	if !errors.Is(err, blaise.ErrDataLoading) {
+		t.Errorf("got error %v while loading, want ErrDataLoading", err)
+	}
+
+	server.SetLoading(false)
+	if _, err := client.Routing(context.Background(), odenplan, tekniska, "08:00:00", true, 1); err != nil {
+		t.Errorf("routing failed once loaded: %v", err)
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	client, server := newClient(t)
+	server.SetAge(30 * 3600)
+	age, err := client.GetAge(context.Background())
+	if err != nil || age != 30*3600 {
+		t.Fatalf("got age %d, %v, want %d", age, err, 30*3600)
+	}
+
+	server.Fail("/gtfs/fetch-url", http.StatusServiceUnavailable)
+	if err := client.TriggerRefresh(context.Background(), "https://example.com/gtfs.zip"); err == nil {
+		t.Error("a failed refresh was retried or ignored")
+	}
+	if err := client.TriggerRefresh(context.Background(), "https://example.com/gtfs.zip"); err != nil {
+		t.Fatalf("refresh failed: %v", err)
+	}
+	if refreshes := server.Refreshes(); len(refreshes) != 1 || refreshes[0] != "https://example.com/gtfs.zip" {
+		t.Errorf("server saw refreshes %v", refreshes)
+	}
+	if age, _ := client.GetAge(context.Background()); age != 0 {
+		t.Errorf("age is %d after a refresh, want 0", age)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	client, server := newClient(t)
+	client.Policies[blaise.CallRouting] = blaise.Policy{Timeout: time.Second, MaxAttempts: 1}
+	client.Breaker = blaise.NewCircuitBreaker(2, 50*time.Millisecond)
+
+	server.Fail("/routing", http.StatusInternalServerError, http.StatusInternalServerError)
+	for range 2 {
+		client.Routing(context.Background(), odenplan, tekniska, "08:00:00", true, 1)
+	}
+	if client.Healthy() {
+		t.Fatal("client is healthy after reaching the failure threshold")
+	}
+	_, err := client.Routing(context.Background(), odenplan, tekniska, "08:00:00", true, 1)
+	if !errors.Is(err, blaise.ErrCircuitOpen) {
+		t.Errorf("got error %v with the circuit open, want ErrCircuitOpen", err)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if state := client.CircuitState(); state != blaise.CircuitHalfOpen {
+		t.Errorf("circuit is %s after the cooldown, want half-open", state)
+	}
+	if _, err := client.Routing(context.Background(), odenplan, tekniska, "08:00:00", true, 1); err != nil {
+		t.Fatalf("probe failed: %v", err)
+	}
+	if state := client.CircuitState(); state != blaise.CircuitClosed {
+		t.Errorf("circuit is %s after a good probe, want closed", state)
+	}
+}
+
+func TestCircuitBreakerIgnoresCallers(t *testing.T) {
+	client, server := newClient(t)
+	client.Policies[blaise.CallRouting] = blaise.Policy{Timeout: time.Second, MaxAttempts: 1}
+	client.Breaker = blaise.NewCircuitBreaker(2, time.Minute)
+	route := func(ctx context.Context) error {
+		_, err := client.Routing(ctx, odenplan, tekniska, "08:00:00", true, 1)
+		return err
+	}
+
+	// A caller that can't wait long enough is not blaise failing
+	server.SetLatency(100 * time.Millisecond)
+	for range 3 {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		err := route(ctx)
+		cancel()
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("got error %v, want the caller's deadline", err)
+		}
+	}
+	if !client.Healthy() {
+		t.Fatal("the caller's deadline opened the circuit")
+	}
+	server.SetLatency(0)
+
+	// A malformed body is no sign of health, the failures before it still count
+	server.Fail("/routing", http.StatusInternalServerError)
+	route(context.Background())
+	server.SetMalformed("/routing", true)
+	if err := route(context.Background()); err == nil {
+		t.Fatal("decoded a malformed body")
+	}
+	server.SetMalformed("/routing", false)
+	server.Fail("/routing", http.StatusInternalServerError)
+	route(context.Background())
+	if client.Healthy() {
+		t.Error("a malformed body reset the failures")
+	}
+}
@@ -10,37 +10,29 @@ import (
 )
 
 func (c *Client) GetAge(ctx context.Context) (uint32, error) {
-	req, _ := http.NewRequestWithContext(ctx, "GET", c.BaseURL+"/gtfs/age", nil)
-	resp, err := c.HTTPClient.Do(req)
-	if err != nil {
-		return 0, err
-	}
-	defer resp.Body.Close()
-	bodyBytes, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return 0, err
-	}
+	var age uint32
+	err := c.do(ctx, CallData, "/gtfs/age", nil, func(resp *http.Response) error {
+		bodyBytes, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
 
-	val, err := strconv.ParseUint(strings.TrimSpace(string(bodyBytes)), 10, 32)
-	if err != nil {
-		return 0, fmt.Errorf("failed to parse age: %w", err)
-	}
-	return uint32(val), nil
+		val, err := strconv.ParseUint(strings.TrimSpace(string(bodyBytes)), 10, 32)
+		if err != nil {
+			return fmt.Errorf("failed to parse age: %w", err)
+		}
+		age = uint32(val)
+		return nil
+	})
+	return age, err
 }
 
 func (c *Client) TriggerRefresh(ctx context.Context, url string) error {
-	req, _ := http.NewRequestWithContext(ctx, "GET", c.BaseURL+"/gtfs/fetch-url", nil)
-	q := req.URL.Query()
-	q.Add("q", url)
-	req.URL.RawQuery = q.Encode()
-	resp, err := c.HTTPClient.Do(req)
+	err := c.do(ctx, CallRefresh, "/gtfs/fetch-url", map[string]string{"q": url}, func(resp *http.Response) error {
+		return nil
+	})
 	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
-		return fmt.Errorf("failed to trigger refresh, status: %d", resp.StatusCode)
+		return fmt.Errorf("failed to trigger refresh: %w", err)
 	}
 	return nil
 }
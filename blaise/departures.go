@@ -0,0 +1,29 @@
+package blaise
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// Departures returns the next count departures from the stop or area with the
+// given id, leaving at or after timeStr (HH:MM:SS).
+func (c *Client) Departures(ctx context.Context, stop string, timeStr string, count int) ([]Departure, error) {
+	var departures []Departure
+	err := c.do(ctx, CallDepartures, "/departures", map[string]string{
+		"from":  stop,
+		"at":    timeStr,
+		"count": strconv.Itoa(count),
+	}, func(resp *http.Response) error {
+		if err := json.NewDecoder(resp.Body).Decode(&departures); err != nil {
+			return fmt.Errorf("failed to decode departures: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return departures, nil
+}
@@ -0,0 +1,98 @@
+package blaise
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+var (
+	ErrNoRoute         = errors.New("no route found")
+	ErrUnknownLocation = errors.New("unknown location")
+	ErrUnavailable     = errors.New("blaise is unavailable")
+	ErrDataLoading     = errors.New("blaise is loading data")
+	ErrBadRequest      = errors.New("bad request")
+)
+
+// Error is a non successful response from blaise. Kind is one of the Err
+// values above so callers can use errors.Is.
+type Error struct {
+	StatusCode int
+	Message    string
+	Kind       error
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("blaise: %s (status %d)", e.Kind, e.StatusCode)
+	}
+	return fmt.Sprintf("blaise: %s (status %d): %s", e.Kind, e.StatusCode, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+// checkResponse returns an *Error for every non 2xx response.
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	message := parseErrorMessage(body)
+	return &Error{
+		StatusCode: resp.StatusCode,
+		Message:    message,
+		Kind:       classify(resp.StatusCode, message),
+	}
+}
+
+// parseErrorMessage reads the message out of a JSON error body, falling back
+// to the body as plain text.
+func parseErrorMessage(body []byte) string {
+	var payload struct {
+		Error   string `json:"error"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &payload); err == nil {
+		if payload.Error != "" {
+			return payload.Error
+		}
+		if payload.Message != "" {
+			return payload.Message
+		}
+	}
+	return strings.TrimSpace(string(body))
+}
+
+func classify(status int, message string) error {
+	lower := strings.ToLower(message)
+	aboutLocation := strings.Contains(lower, "location") || strings.Contains(lower, "area") || strings.Contains(lower, "stop")
+	switch {
+	case status == http.StatusNotFound && aboutLocation:
+		return ErrUnknownLocation
+	case status == http.StatusNotFound:
+		return ErrNoRoute
+	case status == http.StatusServiceUnavailable && strings.Contains(lower, "load"):
+		return ErrDataLoading
+	case status >= 500:
+		return ErrUnavailable
+	case (status == http.StatusBadRequest || status == http.StatusUnprocessableEntity) && aboutLocation && strings.Contains(lower, "unknown"):
+		return ErrUnknownLocation
+	default:
+		return ErrBadRequest
+	}
+}
+
+// transportError wraps errors from the HTTP client, blaise is unreachable
+// unless the caller gave up on its own.
+func transportError(err error) error {
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrUnavailable, err)
+}
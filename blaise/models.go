@@ -2,17 +2,42 @@ package blaise
 
 import (
 	"fmt"
+	"slices"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/bwmarrin/discordgo"
 )
 
+// Time is seconds since the start of the service day. Like in GTFS it can go
+// past 24:00:00 for trips that run over midnight, 25:10:00 is ten past one
+// the next morning.
 type Time uint32
 
+// Day is the length of a service day, a Time at or past it is on a later
+// calendar day.
+const Day Time = 24 * 60 * 60
+
+// Days is how many calendar days after the service day t falls on.
+func (t Time) Days() int {
+	return int(t / Day)
+}
+
+// TimeOfDay wraps t to the clock time of the calendar day it falls on.
+func (t Time) TimeOfDay() Time {
+	return t % Day
+}
+
+// ToHMSString formats t as a clock time, marking times that fall on a later
+// calendar day, e.g. "01:10:00 (+1)".
 func (t Time) ToHMSString() string {
-	h := t / 3600
-	m := (t % 3600) / 60
-	s := t % 60
+	clock := t.TimeOfDay()
+	h := clock / 3600
+	m := (clock % 3600) / 60
+	s := clock % 60
+	if days := t.Days(); days > 0 {
+		return fmt.Sprintf("%02d:%02d:%02d (+%d)", h, m, s, days)
+	}
 	return fmt.Sprintf("%02d:%02d:%02d", h, m, s)
 }
 
@@ -47,6 +72,22 @@ type Leg struct {
 	HeadSign      *string  `json:"head_sign"`
 	LongName      *string  `json:"long_name"`
 	ShortName     *string  `json:"short_name"`
+	// TripID and RouteID are the GTFS ids of transit legs, used to match
+	// realtime updates. Older blaise versions leave them out.
+	TripID  *string `json:"trip_id,omitempty"`
+	RouteID *string `json:"route_id,omitempty"`
+}
+
+// Departure is a vehicle leaving a stop, as listed on a departure board.
+type Departure struct {
+	Stop          Location `json:"stop"`
+	DepartureTime Time     `json:"departure_time"`
+	Mode          string   `json:"mode"`
+	HeadSign      *string  `json:"head_sign"`
+	LongName      *string  `json:"long_name"`
+	ShortName     *string  `json:"short_name"`
+	TripID        *string  `json:"trip_id,omitempty"`
+	RouteID       *string  `json:"route_id,omitempty"`
 }
 
 type Stop struct {
@@ -62,6 +103,88 @@ type Shape struct {
 	DistanceTraveld float32  `json:"distance_traveld"`
 }
 
+// IsTransit reports whether the leg is spent on a vehicle rather than walking.
+func (l Leg) IsTransit() bool {
+	return l.Mode != "Walk" && l.Mode != "Transfer"
+}
+
+// Transfers is the number of times the traveller changes vehicle.
+func (i Itinerary) Transfers() int {
+	count := 0
+	for _, leg := range i.Legs {
+		if leg.IsTransit() {
+			count++
+		}
+	}
+	return max(count-1, 0)
+}
+
+// Lines is the lines of the transit legs in order, e.g. ["Bus 50", "Tram 7"].
+func (i Itinerary) Lines() []string {
+	lines := make([]string, 0, len(i.Legs))
+	for _, leg := range i.Legs {
+		if !leg.IsTransit() {
+			continue
+		}
+		if leg.ShortName != nil {
+			lines = append(lines, leg.Mode+" "+*leg.ShortName)
+		} else {
+			lines = append(lines, leg.Mode)
+		}
+	}
+	return lines
+}
+
+// Summary is a one line description of the itinerary, e.g. "🚌 50 › 🚋 7".
+func (i Itinerary) Summary() string {
+	parts := make([]string, 0, len(i.Legs))
+	for _, leg := range i.Legs {
+		if !leg.IsTransit() {
+			continue
+		}
+		if leg.ShortName != nil {
+			parts = append(parts, getModeEmoji(leg.Mode)+" "+*leg.ShortName)
+		} else {
+			parts = append(parts, getModeEmoji(leg.Mode)+" "+leg.Mode)
+		}
+	}
+	if len(parts) == 0 {
+		return getModeEmoji("Walk") + " Walk"
+	}
+	return strings.Join(parts, " › ")
+}
+
+// ClosestItinerary picks the option most like previous, so re-routing a trip
+// keeps the alternative the user chose. It prefers the same lines, then the
+// same number of transfers, and then the closest departure.
+func ClosestItinerary(previous Itinerary, options []Itinerary) Itinerary {
+	if len(options) == 0 {
+		return previous
+	}
+	lines := previous.Lines()
+	best, bestScore := options[0], -1
+	for _, option := range options {
+		score := 0
+		if slices.Equal(option.Lines(), lines) {
+			score += 2
+		}
+		if option.Transfers() == previous.Transfers() {
+			score++
+		}
+		if score > bestScore || (score == bestScore && timeDistance(option.DepartureTime, previous.DepartureTime) < timeDistance(best.DepartureTime, previous.DepartureTime)) {
+			best, bestScore = option, score
+		}
+	}
+	return best
+}
+
+func timeDistance(a, b Time) Time {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
+
 func getModeEmoji(mode string) string {
 	switch mode {
 	case "Tram":
@@ -131,3 +254,65 @@ func IteniraryToEmbedFields(itinerary Itinerary) []*discordgo.MessageEmbedField
 	}
 	return fields
 }
+
+// maxDepartureFields is how many lines a departure board shows, Discord
+// allows 25 fields per embed.
+const maxDepartureFields = 25
+
+// maxFieldValue is the longest value Discord takes in an embed field.
+const maxFieldValue = 1024
+
+// DeparturesToEmbedFields groups the departures by line and direction, in the
+// order they first leave, with the minutes until each one as seen at now.
+func DeparturesToEmbedFields(departures []Departure, now Time) []*discordgo.MessageEmbedField {
+	fields := make([]*discordgo.MessageEmbedField, 0, len(departures))
+	byLine := make(map[string]*strings.Builder)
+	for _, departure := range departures {
+		emoji := getModeEmoji(departure.Mode)
+
+		// e.g. "🚋 7 (Towards Centralen)", the same as the legs of an itinerary
+		title := fmt.Sprintf("%s %s", emoji, departure.Mode)
+		if departure.ShortName != nil {
+			title = fmt.Sprintf("%s %s", emoji, *departure.ShortName)
+		}
+		if departure.HeadSign != nil {
+			title += fmt.Sprintf(" (Towards %s)", *departure.HeadSign)
+		}
+
+		sb, ok := byLine[title]
+		if !ok {
+			if len(fields) == maxDepartureFields {
+				continue
+			}
+			sb = &strings.Builder{}
+			byLine[title] = sb
+			fields = append(fields, &discordgo.MessageEmbedField{Name: title})
+		} else {
+			sb.WriteString(" • ")
+		}
+		fmt.Fprintf(sb, "`%s`", departure.DepartureTime.ToHMSString()[:5])
+		// Boards around midnight mix times from both sides of it
+		wait := int(departure.DepartureTime.TimeOfDay()) - int(now.TimeOfDay())
+		if wait < -int(Day)/2 {
+			wait += int(Day)
+		}
+		switch {
+		case wait < 60:
+			sb.WriteString(" (now)")
+		case wait < 60*60:
+			fmt.Fprintf(sb, " (%d min)", wait/60)
+		}
+	}
+	for _, field := range fields {
+		field.Value = byLine[field.Name].String()
+		if len(field.Value) > maxFieldValue {
+			// Cut on a rune boundary, the separators and names aren't ASCII
+			cut := maxFieldValue - len("...")
+			for !utf8.RuneStart(field.Value[cut]) {
+				cut--
+			}
+			field.Value = field.Value[:cut] + "..."
+		}
+	}
+	return fields
+}
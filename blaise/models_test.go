@@ -0,0 +1,37 @@
+package blaise_test
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/vincbro/pascal/blaise"
+)
+
+func TestDepartureFieldsTruncate(t *testing.T) {
+	line, headSign := "4", "Radiohuset via Södermalm"
+	// Each board is seen from another minute, so the waits shift the cut
+	// across every byte of an entry
+	departures := make([]blaise.Departure, 0, 150)
+	for i := range 150 {
+		departures = append(departures, blaise.Departure{
+			DepartureTime: blaise.Time(8*3600 + i*60),
+			Mode:          "Bus",
+			ShortName:     &line,
+			HeadSign:      &headSign,
+		})
+	}
+	for minute := range 60 {
+		fields := blaise.DeparturesToEmbedFields(departures, blaise.Time(7*3600+minute*60))
+		if len(fields) != 1 {
+			t.Fatalf("got %d fields for one line", len(fields))
+		}
+		value := fields[0].Value
+		if !utf8.ValidString(value) {
+			t.Fatalf("board at 07:%02d is invalid UTF-8: %q", minute, value[len(value)-10:])
+		}
+		if len(value) > 1024 || !strings.HasSuffix(value, "...") {
+			t.Errorf("board at 07:%02d is %d bytes ending in %q", minute, len(value), value[len(value)-3:])
+		}
+	}
+}
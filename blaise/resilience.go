@@ -0,0 +1,253 @@
+package blaise
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without calling blaise while the circuit breaker
+// is open. It wraps ErrUnavailable.
+var ErrCircuitOpen = fmt.Errorf("%w: circuit open", ErrUnavailable)
+
+type CallType int
+
+const (
+	CallRouting CallType = iota
+	CallSearch
+	CallData
+	CallRefresh
+	CallDepartures
+)
+
+// Policy configures how a type of call is made. Every attempt gets its own
+// Timeout, failed attempts are retried with jittered exponential backoff
+// starting at BaseDelay and capped at MaxDelay.
+type Policy struct {
+	Timeout     time.Duration
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// DefaultPolicies retries the idempotent GETs. Triggering a refresh starts a
+// reload on the server so it is never retried.
+func DefaultPolicies() map[CallType]Policy {
+	return map[CallType]Policy{
+		CallRouting:    {Timeout: 5 * time.Second, MaxAttempts: 3, BaseDelay: 250 * time.Millisecond, MaxDelay: 2 * time.Second},
+		CallSearch:     {Timeout: 2 * time.Second, MaxAttempts: 2, BaseDelay: 100 * time.Millisecond, MaxDelay: 500 * time.Millisecond},
+		CallData:       {Timeout: 2 * time.Second, MaxAttempts: 3, BaseDelay: 500 * time.Millisecond, MaxDelay: 4 * time.Second},
+		CallRefresh:    {Timeout: 10 * time.Second, MaxAttempts: 1},
+		CallDepartures: {Timeout: 3 * time.Second, MaxAttempts: 2, BaseDelay: 100 * time.Millisecond, MaxDelay: 500 * time.Millisecond},
+	}
+}
+
+func (p Policy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay << (attempt - 1)
+	if delay <= 0 || delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	// Half fixed, half random so clients that failed together don't retry together
+	return delay/2 + rand.N(delay/2+1)
+}
+
+// retryable reports whether err is worth another attempt, only failures of
+// blaise itself are.
+func retryable(err error) bool {
+	return !errors.Is(err, ErrCircuitOpen) && (errors.Is(err, ErrUnavailable) || errors.Is(err, ErrDataLoading))
+}
+
+type CircuitState int
+
+const (
+	CircuitClosed CircuitState = iota
+	CircuitOpen
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreaker fails calls fast once blaise has failed Threshold times in a
+// row. After Cooldown a single probe call is let through, closing the circuit
+// again if it succeeds.
+type CircuitBreaker struct {
+	Threshold int
+	Cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	open     bool
+	probing  bool
+}
+
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		Threshold: threshold,
+		Cooldown:  cooldown,
+	}
+}
+
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state()
+}
+
+func (b *CircuitBreaker) state() CircuitState {
+	if !b.open {
+		return CircuitClosed
+	}
+	if time.Since(b.openedAt) >= b.Cooldown {
+		return CircuitHalfOpen
+	}
+	return CircuitOpen
+}
+
+// Allow returns ErrCircuitOpen if a call should not be made right now.
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state() {
+	case CircuitOpen:
+		return ErrCircuitOpen
+	case CircuitHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// Record updates the breaker with the outcome of an allowed call. Only
+// failures of blaise itself count and only a decoded response or an error
+// blaise answered with, like a missing route, shows it is healthy. Anything
+// else, e.g. a body that failed to decode, leaves the breaker as it was.
+func (b *CircuitBreaker) Record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	// The caller giving up says nothing about blaise
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	var answer *Error
+	switch {
+	case retryable(err):
+		b.failures++
+		if b.open || b.failures >= b.Threshold {
+			b.open = true
+			b.openedAt = time.Now()
+		}
+	case err == nil || errors.As(err, &answer):
+		b.failures = 0
+		b.open = false
+	}
+}
+
+// release ends an allowed call without recording an outcome.
+func (b *CircuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+func (c *Client) policy(call CallType) Policy {
+	if policy, ok := c.Policies[call]; ok {
+		return policy
+	}
+	return Policy{Timeout: 2 * time.Second, MaxAttempts: 1}
+}
+
+// CircuitState is the state of the client's circuit breaker.
+func (c *Client) CircuitState() CircuitState {
+	if c.Breaker == nil {
+		return CircuitClosed
+	}
+	return c.Breaker.State()
+}
+
+// Healthy reports whether calls to blaise are currently let through.
+func (c *Client) Healthy() bool {
+	return c.CircuitState() != CircuitOpen
+}
+
+// do makes a GET request to path following the policy of call. decode is only
+// called for successful responses and runs before the attempt's timeout is
+// released.
+func (c *Client) do(ctx context.Context, call CallType, path string, query map[string]string, decode func(resp *http.Response) error) error {
+	policy := c.policy(call)
+	for attempt := 1; ; attempt++ {
+		err := c.attempt(ctx, policy, path, query, decode)
+		if err == nil || !retryable(err) || attempt >= policy.MaxAttempts {
+			return err
+		}
+
+		select {
+		case <-time.After(policy.backoff(attempt)):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+func (c *Client) attempt(ctx context.Context, policy Policy, path string, query map[string]string, decode func(resp *http.Response) error) (err error) {
+	if c.Breaker != nil {
+		if err := c.Breaker.Allow(); err != nil {
+			return err
+		}
+		parent := ctx
+		defer func() {
+			// The caller's own deadline passing says nothing about blaise
+			if parent.Err() != nil {
+				c.Breaker.release()
+				return
+			}
+			c.Breaker.Record(err)
+		}()
+	}
+
+	if policy.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, policy.Timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", c.BaseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	q := req.URL.Query()
+	for k, v := range query {
+		q.Add(k, v)
+	}
+	req.URL.RawQuery = q.Encode()
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return transportError(err)
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp); err != nil {
+		return err
+	}
+	return decode(resp)
+}
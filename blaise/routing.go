@@ -1,32 +1,65 @@
 package blaise
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 )
 
-func (c *Client) Routing(ctx context.Context, from, to, timeStr string, departure bool) (Itinerary, error) {
-	req, _ := http.NewRequestWithContext(ctx, "GET", c.BaseURL+"/routing", nil)
-
-	q := req.URL.Query()
-	q.Add("from", from)
-	q.Add("to", to)
+// Routing asks blaise for up to count alternative itineraries, best first.
+func (c *Client) Routing(ctx context.Context, from, to, timeStr string, departure bool, count int) ([]Itinerary, error) {
+	query := map[string]string{
+		"from":  from,
+		"to":    to,
+		"count": strconv.Itoa(count),
+	}
 	if departure {
-		q.Add("departure_at", timeStr)
+		query["departure_at"] = timeStr
 	} else {
-		q.Add("arrive_at", timeStr)
+		query["arrive_at"] = timeStr
 	}
-	req.URL.RawQuery = q.Encode()
-	resp, err := c.HTTPClient.Do(req)
+
+	var iteniraries []Itinerary
+	err := c.do(ctx, CallRouting, "/routing", query, func(resp *http.Response) error {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		// Older blaise versions ignore count and answer with a single itinerary
+		body = bytes.TrimSpace(body)
+		if len(body) > 0 && body[0] == '{' {
+			var itenirary Itinerary
+			if err := json.Unmarshal(body, &itenirary); err != nil {
+				return fmt.Errorf("failed to decode itinerary: %w", err)
+			}
+			iteniraries = []Itinerary{itenirary}
+			return nil
+		}
+		if err := json.Unmarshal(body, &iteniraries); err != nil {
+			return fmt.Errorf("failed to decode itineraries: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return Itinerary{}, err
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	var itenirary Itinerary
-	if err := json.NewDecoder(resp.Body).Decode(&itenirary); err != nil {
-		return Itinerary{}, err
+	// Never hand out an empty itinerary, it would be saved as a trip at 00:00:00
+	found := make([]Itinerary, 0, len(iteniraries))
+	for _, itenirary := range iteniraries {
+		if len(itenirary.Legs) > 0 {
+			found = append(found, itenirary)
+		}
+	}
+	if len(found) == 0 {
+		return nil, ErrNoRoute
+	}
+	if count > 0 && len(found) > count {
+		found = found[:count]
 	}
-	return itenirary, nil
+	return found, nil
 }
@@ -0,0 +1,262 @@
+// Package calendar loads the public holidays trips should not run on.
+package calendar
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DateLayout is the layout of the dates in a JSON calendar, the same as
+// database.DateLayout.
+const DateLayout = "2006-01-02"
+
+// Holiday is a single entry of a JSON calendar. End is inclusive and may be
+// left out for single days.
+type Holiday struct {
+	Name string `json:"name"`
+	Date string `json:"date"`
+	End  string `json:"end,omitempty"`
+}
+
+// Calendar is a set of holidays by date. A nil Calendar has no holidays.
+type Calendar struct {
+	holidays map[string]string
+	// recurring are the repeating events without an end, they are matched
+	// when asked instead of being written out day by day
+	recurring []*recurrence
+}
+
+func New(holidays []Holiday) (*Calendar, error) {
+	c := &Calendar{holidays: make(map[string]string)}
+	for _, holiday := range holidays {
+		start, err := time.Parse(DateLayout, holiday.Date)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date of %q: %w", holiday.Name, err)
+		}
+		end := start
+		if holiday.End != "" {
+			end, err = time.Parse(DateLayout, holiday.End)
+			if err != nil {
+				return nil, fmt.Errorf("invalid end of %q: %w", holiday.Name, err)
+			}
+		}
+		c.add(holiday.Name, start, end.AddDate(0, 0, 1))
+	}
+	return c, nil
+}
+
+// Load reads an ICS or JSON calendar, picked by the extension of path.
+func Load(path string) (*Calendar, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ics", ".ical":
+		return ParseICS(file)
+	case ".json":
+		return ParseJSON(file)
+	default:
+		return nil, fmt.Errorf("unknown calendar format %q, use .ics or .json", filepath.Ext(path))
+	}
+}
+
+// ParseJSON reads a list of holidays, e.g.
+//
+//	[{"name": "Christmas Day", "date": "2025-12-25"}]
+func ParseJSON(r io.Reader) (*Calendar, error) {
+	holidays := []Holiday{}
+	if err := json.NewDecoder(r).Decode(&holidays); err != nil {
+		return nil, fmt.Errorf("failed to decode calendar: %w", err)
+	}
+	return New(holidays)
+}
+
+// ParseICS reads the all day events of an iCalendar file. Only DTSTART, DTEND,
+// SUMMARY, RRULE and EXDATE are used. Recurring events may repeat daily,
+// weekly, monthly or yearly on the day they start, with INTERVAL, COUNT and
+// UNTIL, rules picking other days with BYDAY and the like are rejected.
+func ParseICS(r io.Reader) (*Calendar, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Calendar{holidays: make(map[string]string)}
+	inEvent := false
+	var name, rule string
+	var start, end time.Time
+	var excluded map[string]bool
+	for _, line := range lines {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		// Drop parameters, e.g. DTSTART;VALUE=DATE
+		key, _, _ = strings.Cut(strings.ToUpper(key), ";")
+		switch key {
+		case "BEGIN":
+			if value == "VEVENT" {
+				inEvent = true
+				name, rule, start, end = "", "", time.Time{}, time.Time{}
+				excluded = make(map[string]bool)
+			}
+		case "END":
+			if value != "VEVENT" || !inEvent {
+				continue
+			}
+			inEvent = false
+			if start.IsZero() {
+				return nil, fmt.Errorf("event %q has no DTSTART", name)
+			}
+			// DTEND is exclusive and defaults to a single day
+			if !end.After(start) {
+				end = start.AddDate(0, 0, 1)
+			}
+			if rule == "" {
+				c.add(name, start, end)
+				continue
+			}
+			r, err := parseRule(rule, start)
+			if err != nil {
+				return nil, fmt.Errorf("invalid RRULE of %q: %w", name, err)
+			}
+			r.name = name
+			r.days = int(end.Sub(start).Hours() / 24)
+			r.excluded = excluded
+			c.addRecurrence(r)
+		case "SUMMARY":
+			if inEvent {
+				name = unescape(value)
+			}
+		case "RRULE":
+			if inEvent {
+				rule = value
+			}
+		case "EXDATE":
+			if !inEvent {
+				continue
+			}
+			for _, value := range strings.Split(value, ",") {
+				date, err := parseICSDate(value)
+				if err != nil {
+					return nil, fmt.Errorf("invalid EXDATE %q: %w", value, err)
+				}
+				excluded[date.Format(DateLayout)] = true
+			}
+		case "DTSTART", "DTEND":
+			if !inEvent {
+				continue
+			}
+			date, err := parseICSDate(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s %q: %w", key, value, err)
+			}
+			if key == "DTSTART" {
+				start = date
+			} else {
+				end = date
+			}
+		}
+	}
+	return c, nil
+}
+
+// unfold joins the lines that were split by continuation lines starting with
+// a space or tab.
+func unfold(r io.Reader) ([]string, error) {
+	lines := make([]string, 0)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read calendar: %w", err)
+	}
+	return lines, nil
+}
+
+// parseICSDate reads the day of a DATE or DATE-TIME value, the time of day is
+// ignored.
+func parseICSDate(value string) (time.Time, error) {
+	if len(value) < 8 {
+		return time.Time{}, fmt.Errorf("too short")
+	}
+	return time.Parse("20060102", value[:8])
+}
+
+func unescape(value string) string {
+	return strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(value)
+}
+
+// add marks every day from start up to, but not including, end.
+func (c *Calendar) add(name string, start, end time.Time) {
+	if name == "" {
+		name = "Holiday"
+	}
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		c.holidays[day.Format(DateLayout)] = name
+	}
+}
+
+// addRecurrence writes out the occurrences of a recurring event that ends
+// and keeps the ones that don't to match later.
+func (c *Calendar) addRecurrence(r *recurrence) {
+	if r.name == "" {
+		r.name = "Holiday"
+	}
+	if r.count == 0 && r.until.IsZero() {
+		c.recurring = append(c.recurring, r)
+		return
+	}
+	for _, start := range r.occurrences() {
+		c.add(r.name, start, start.AddDate(0, 0, r.days))
+	}
+}
+
+// Holiday returns the name of the holiday on the day of date, if any.
+func (c *Calendar) Holiday(date time.Time) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	if name, ok := c.holidays[date.Format(DateLayout)]; ok {
+		return name, true
+	}
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	for _, r := range c.recurring {
+		if r.covers(day) {
+			return r.name, true
+		}
+	}
+	return "", false
+}
+
+// Len is the number of days in the calendar, not counting the recurring
+// events without an end.
+func (c *Calendar) Len() int {
+	if c == nil {
+		return 0
+	}
+	return len(c.holidays)
+}
+
+// Recurring is the number of recurring events without an end.
+func (c *Calendar) Recurring() int {
+	if c == nil {
+		return 0
+	}
+	return len(c.recurring)
+}
@@ -0,0 +1,213 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// holidays lists the days of 2026-2028 that c has a holiday on, with its name.
+func holidays(c *Calendar) map[string]string {
+	days := make(map[string]string)
+	for day := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC); day.Year() < 2029; day = day.AddDate(0, 0, 1) {
+		if name, ok := c.Holiday(day); ok {
+			days[day.Format(DateLayout)] = name
+		}
+	}
+	return days
+}
+
+func checkHolidays(t *testing.T, c *Calendar, want map[string]string) {
+	t.Helper()
+	got := holidays(c)
+	for day, name := range want {
+		if got[day] != name {
+			t.Errorf("%s is %q, want %q", day, got[day], name)
+		}
+	}
+	for day, name := range got {
+		if _, ok := want[day]; !ok {
+			t.Errorf("%s is %q, want no holiday", day, name)
+		}
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	c, err := ParseJSON(strings.NewReader(`[
+		{"name": "Christmas", "date": "2026-12-24", "end": "2026-12-26"},
+		{"name": "New Year's Day", "date": "2027-01-01"}
+	]`))
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	checkHolidays(t, c, map[string]string{
+		"2026-12-24": "Christmas",
+		"2026-12-25": "Christmas",
+		"2026-12-26": "Christmas",
+		"2027-01-01": "New Year's Day",
+	})
+	if c.Len() != 4 {
+		t.Errorf("got %d days, want 4", c.Len())
+	}
+
+	for _, input := range []string{
+		`{"name": "Not a list"}`,
+		`[{"name": "Bad date", "date": "24/12/2026"}]`,
+		`[{"name": "Bad end", "date": "2026-12-24", "end": "soon"}]`,
+	} {
+		if _, err := ParseJSON(strings.NewReader(input)); err == nil {
+			t.Errorf("parsed %s", input)
+		}
+	}
+}
+
+// ics wraps events in a calendar with CRLF line endings, the tabs indenting
+// the events are dropped.
+func ics(events ...string) string {
+	lines := []string{"BEGIN:VCALENDAR", "VERSION:2.0"}
+	for _, event := range events {
+		lines = append(lines, "BEGIN:VEVENT")
+		lines = append(lines, strings.Split(strings.TrimSpace(event), "\n")...)
+		lines = append(lines, "END:VEVENT")
+	}
+	lines = append(lines, "END:VCALENDAR")
+	for i := range lines {
+		lines[i] = strings.TrimLeft(lines[i], "\t")
+	}
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
+
+func TestParseICS(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  map[string]string
+	}{
+		{
+			name: "single days",
+			input: ics(`
+				DTSTART;VALUE=DATE:20261225
+				SUMMARY:Christmas Day`, `
+				SUMMARY:Boxing Day
+				DTSTART:20261226T000000Z`),
+			want: map[string]string{"2026-12-25": "Christmas Day", "2026-12-26": "Boxing Day"},
+		},
+		{
+			name: "DTEND is exclusive",
+			input: ics(`
+				SUMMARY:Sportlov
+				DTSTART;VALUE=DATE:20270222
+				DTEND;VALUE=DATE:20270225`),
+			want: map[string]string{"2027-02-22": "Sportlov", "2027-02-23": "Sportlov", "2027-02-24": "Sportlov"},
+		},
+		{
+			name:  "folded and escaped",
+			input: ics("SUMMARY:Christmas\\, Eve and\n  the day after\nDTSTART:20261224"),
+			want:  map[string]string{"2026-12-24": "Christmas, Eve and the day after"},
+		},
+		{
+			name:  "no summary",
+			input: ics("DTSTART:20260606"),
+			want:  map[string]string{"2026-06-06": "Holiday"},
+		},
+		{
+			name: "yearly without an end",
+			input: ics(`
+				SUMMARY:National Day
+				DTSTART;VALUE=DATE:20200606
+				RRULE:FREQ=YEARLY;BYMONTH=6;BYMONTHDAY=6`),
+			want: map[string]string{"2026-06-06": "National Day", "2027-06-06": "National Day", "2028-06-06": "National Day"},
+		},
+		{
+			name: "every other year over two days",
+			input: ics(`
+				SUMMARY:Festival
+				DTSTART:20261231
+				DTEND:20270102
+				RRULE:FREQ=YEARLY;INTERVAL=2`),
+			want: map[string]string{"2026-12-31": "Festival", "2027-01-01": "Festival", "2028-12-31": "Festival"},
+		},
+		{
+			name: "leap day",
+			input: ics(`
+				SUMMARY:Leap Day
+				DTSTART:20240229
+				RRULE:FREQ=YEARLY`),
+			want: map[string]string{"2028-02-29": "Leap Day"},
+		},
+		{
+			name: "monthly on the 31st with a count",
+			input: ics(`
+				SUMMARY:Month end
+				DTSTART:20260131
+				RRULE:FREQ=MONTHLY;COUNT=3`),
+			want: map[string]string{"2026-01-31": "Month end", "2026-03-31": "Month end", "2026-05-31": "Month end"},
+		},
+		{
+			name: "weekly until with an excluded day",
+			input: ics(`
+				SUMMARY:Closed
+				DTSTART:20261005
+				RRULE:FREQ=WEEKLY;INTERVAL=2;UNTIL=20261116T235959Z;WKST=MO
+				EXDATE;VALUE=DATE:20261102`),
+			want: map[string]string{"2026-10-05": "Closed", "2026-10-19": "Closed", "2026-11-16": "Closed"},
+		},
+		{
+			name: "daily without an end",
+			input: ics(`
+				SUMMARY:Every third day
+				DTSTART:20281220
+				RRULE:FREQ=DAILY;INTERVAL=3`),
+			want: map[string]string{"2028-12-20": "Every third day", "2028-12-23": "Every third day", "2028-12-26": "Every third day", "2028-12-29": "Every third day"},
+		},
+		{
+			name:  "outside of an event",
+			input: "BEGIN:VCALENDAR\r\nSUMMARY:Nothing\r\nDTSTART:20261224\r\nEND:VCALENDAR\r\n",
+			want:  map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ParseICS(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("failed to parse: %v", err)
+			}
+			checkHolidays(t, c, tt.want)
+		})
+	}
+}
+
+func TestParseICSErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"no DTSTART", ics("SUMMARY:Christmas")},
+		{"bad DTSTART", ics("DTSTART:2026-12-24")},
+		{"bad EXDATE", ics("DTSTART:20261224\nRRULE:FREQ=YEARLY\nEXDATE:soon")},
+		{"no FREQ", ics("DTSTART:20261224\nRRULE:INTERVAL=2")},
+		{"hourly", ics("DTSTART:20261224\nRRULE:FREQ=HOURLY")},
+		{"zero interval", ics("DTSTART:20261224\nRRULE:FREQ=YEARLY;INTERVAL=0")},
+		{"bad count", ics("DTSTART:20261224\nRRULE:FREQ=YEARLY;COUNT=many")},
+		// Midsummer Eve is the Friday between the 19th and 25th of June
+		{"other days", ics("DTSTART:20260619\nRRULE:FREQ=YEARLY;BYMONTH=6;BYDAY=FR;BYMONTHDAY=19,20,21,22,23,24,25")},
+		{"other month", ics("DTSTART:20261224\nRRULE:FREQ=YEARLY;BYMONTH=1")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseICS(strings.NewReader(tt.input)); err == nil {
+				t.Error("parsed without an error")
+			}
+		})
+	}
+}
+
+func TestNilCalendar(t *testing.T) {
+	var c *Calendar
+	if name, ok := c.Holiday(time.Now()); ok {
+		t.Errorf("nil calendar has holiday %q", name)
+	}
+	if c.Len() != 0 || c.Recurring() != 0 {
+		t.Error("nil calendar has days")
+	}
+}
@@ -0,0 +1,168 @@
+package calendar
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxOccurrences bounds how many days an event with a COUNT is written out
+// for.
+const maxOccurrences = 1000
+
+// recurrence is a recurring all day event, it starts on start and then every
+// interval days, weeks, months or years on the same day.
+type recurrence struct {
+	name     string
+	start    time.Time
+	days     int
+	freq     string
+	interval int
+	// count is the number of occurrences, 0 for no limit
+	count int
+	// until is the last day an occurrence may start on, zero for no limit
+	until    time.Time
+	excluded map[string]bool
+}
+
+// parseRule reads the RRULE of an event starting on start.
+func parseRule(rule string, start time.Time) (*recurrence, error) {
+	r := &recurrence{start: start, interval: 1}
+	for part := range strings.SplitSeq(rule, ";") {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid part %q", part)
+		}
+		var err error
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			r.freq = strings.ToUpper(value)
+			switch r.freq {
+			case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+			default:
+				return nil, fmt.Errorf("unsupported FREQ %q", value)
+			}
+		case "INTERVAL":
+			r.interval, err = strconv.Atoi(value)
+			if err == nil && r.interval < 1 {
+				err = fmt.Errorf("must be at least 1")
+			}
+		case "COUNT":
+			r.count, err = strconv.Atoi(value)
+			if err == nil && r.count < 1 {
+				err = fmt.Errorf("must be at least 1")
+			}
+		case "UNTIL":
+			r.until, err = parseICSDate(value)
+		case "WKST":
+			// Only matters for weekly rules with BYDAY
+		case "BYMONTH", "BYMONTHDAY":
+			// Exports often repeat the day the event starts on, that is
+			// the only day that can be matched
+			if !r.startsOn(strings.ToUpper(key), value) {
+				return nil, fmt.Errorf("%s=%s other than the start is not supported", key, value)
+			}
+		default:
+			return nil, fmt.Errorf("%s is not supported", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q: %w", key, value, err)
+		}
+	}
+	if r.freq == "" {
+		return nil, fmt.Errorf("missing FREQ")
+	}
+	return r, nil
+}
+
+// startsOn reports whether a BYMONTH or BYMONTHDAY value is just the day the
+// event starts on.
+func (r *recurrence) startsOn(key, value string) bool {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return false
+	}
+	if key == "BYMONTH" {
+		return n == int(r.start.Month())
+	}
+	return n == r.start.Day()
+}
+
+// nth is the start of the nth repetition, which doesn't occur when the day
+// doesn't exist that month, e.g. the 31st of April.
+func (r *recurrence) nth(n int) (time.Time, bool) {
+	step := n * r.interval
+	var day time.Time
+	switch r.freq {
+	case "DAILY":
+		return r.start.AddDate(0, 0, step), true
+	case "WEEKLY":
+		return r.start.AddDate(0, 0, 7*step), true
+	case "MONTHLY":
+		day = r.start.AddDate(0, step, 0)
+	default:
+		day = r.start.AddDate(step, 0, 0)
+	}
+	return day, day.Day() == r.start.Day()
+}
+
+// occurrences lists the starts of an event with a COUNT or UNTIL.
+func (r *recurrence) occurrences() []time.Time {
+	starts := make([]time.Time, 0)
+	found := 0
+	for n := 0; len(starts) < maxOccurrences; n++ {
+		start, ok := r.nth(n)
+		if !r.until.IsZero() && start.After(r.until) {
+			break
+		}
+		if !ok {
+			continue
+		}
+		// Excluded days still count towards COUNT
+		found++
+		if !r.excluded[start.Format(DateLayout)] {
+			starts = append(starts, start)
+		}
+		if found == r.count {
+			break
+		}
+	}
+	return starts
+}
+
+// covers reports whether an occurrence of an event without an end covers
+// day, a midnight in UTC.
+func (r *recurrence) covers(day time.Time) bool {
+	for i := range max(r.days, 1) {
+		if r.startsAt(day.AddDate(0, 0, -i)) {
+			return true
+		}
+	}
+	return false
+}
+
+// startsAt reports whether an occurrence starts on day.
+func (r *recurrence) startsAt(day time.Time) bool {
+	if day.Before(r.start) || r.excluded[day.Format(DateLayout)] {
+		return false
+	}
+	switch r.freq {
+	case "DAILY":
+		return daysBetween(r.start, day)%r.interval == 0
+	case "WEEKLY":
+		return day.Weekday() == r.start.Weekday() && daysBetween(r.start, day)/7%r.interval == 0
+	case "MONTHLY":
+		months := (day.Year()-r.start.Year())*12 + int(day.Month()-r.start.Month())
+		return day.Day() == r.start.Day() && months%r.interval == 0
+	default:
+		return day.Month() == r.start.Month() && day.Day() == r.start.Day() && (day.Year()-r.start.Year())%r.interval == 0
+	}
+}
+
+func daysBetween(from, to time.Time) int {
+	return int(to.Sub(from).Hours() / 24)
+}
@@ -1,7 +1,9 @@
 package database
 
 import (
+	"database/sql"
 	"log/slog"
+	"time"
 
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -19,12 +21,74 @@ func NewDatabase(dsn string) (*Database, error) {
 
 	db.AutoMigrate(&User{})
 	db.AutoMigrate(&Trip{})
+	if err := migrateWeekdays(db); err != nil {
+		return nil, err
+	}
+	db.AutoMigrate(&AlertState{})
+	db.AutoMigrate(&Away{})
+	db.AutoMigrate(&SkipDate{})
+	db.AutoMigrate(&Place{})
 
 	return &Database{
 		Client: db,
 	}, nil
 }
 
+// weekdayColumns are the columns trips stored their schedule in before they
+// had a recurrence.
+var weekdayColumns = []string{"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"}
+
+// migrateWeekdays moves the schedule of trips out of the old weekday columns
+// into their recurrence and drops the columns.
+func migrateWeekdays(db *gorm.DB) error {
+	if !db.Migrator().HasColumn(&Trip{}, "monday") {
+		return nil
+	}
+	rows, err := db.Table("trips").Select(append([]string{"id"}, weekdayColumns...)).Rows()
+	if err != nil {
+		return err
+	}
+	recurrences := make(map[string]Recurrence)
+	for rows.Next() {
+		var id string
+		runs := make([]sql.NullBool, len(weekdayColumns))
+		dest := []any{&id}
+		for i := range runs {
+			dest = append(dest, &runs[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			rows.Close()
+			return err
+		}
+		days := make([]time.Weekday, 0, len(runs))
+		for weekday, run := range runs {
+			if run.Bool {
+				days = append(days, time.Weekday(weekday))
+			}
+		}
+		recurrences[id] = WeeklyOn(days...)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for id, recurrence := range recurrences {
+			if err := tx.Model(&Trip{}).Where("id = ?", id).Update("recurrence", recurrence).Error; err != nil {
+				return err
+			}
+		}
+		for _, column := range weekdayColumns {
+			if err := tx.Migrator().DropColumn(&Trip{}, column); err != nil {
+				return err
+			}
+		}
+		slog.Info("Migrated trip weekdays to recurrences", "trips", len(recurrences))
+		return nil
+	})
+}
+
 func (d *Database) GetUser(userID string) (*User, error) {
 	user := &User{}
 	result := d.Client.First(user, User{ID: userID})
@@ -95,10 +159,159 @@ func (d *Database) GetTrip(userID string, tripID string) (*Trip, error) {
 func (d *Database) RemoveTrip(userID string, tripID string) error {
 	trip := &Trip{}
 	result := d.Client.Delete(trip, Trip{UserID: userID, ID: tripID})
-	return result.Error
+	if result.Error != nil {
+		return result.Error
+	}
+	if err := d.RemoveAlertStates(tripID); err != nil {
+		return err
+	}
+	return d.Client.Where("trip_id = ?", tripID).Delete(&SkipDate{}).Error
 }
 
 func (d *Database) UpdateTrip(trip *Trip) error {
 	result := d.Client.Save(trip)
 	return result.Error
 }
+
+func (d *Database) GetAlertState(tripID string, serviceDate string) (*AlertState, error) {
+	state := &AlertState{}
+	result := d.Client.First(state, AlertState{TripID: tripID, ServiceDate: serviceDate})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return state, nil
+}
+
+func (d *Database) SaveAlertState(state *AlertState) error {
+	result := d.Client.Save(state)
+	return result.Error
+}
+
+func (d *Database) RemoveAlertStates(tripID string) error {
+	result := d.Client.Where("trip_id = ?", tripID).Delete(&AlertState{})
+	return result.Error
+}
+
+// PruneAlertStates removes the alert states of every service date before the
+// given one.
+func (d *Database) PruneAlertStates(before string) error {
+	result := d.Client.Where("service_date < ?", before).Delete(&AlertState{})
+	return result.Error
+}
+
+func (d *Database) AddAway(away *Away) error {
+	result := d.Client.Create(away)
+	return result.Error
+}
+
+// GetAways returns the away ranges of the user ordered by when they start.
+func (d *Database) GetAways(userID string) ([]*Away, error) {
+	aways := []*Away{}
+	result := d.Client.Order("start_date").Find(&aways, Away{UserID: userID})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return aways, nil
+}
+
+func (d *Database) RemoveAway(userID string, awayID uint) error {
+	result := d.Client.Where("user_id = ? AND id = ?", userID, awayID).Delete(&Away{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (d *Database) AddSkipDate(skip *SkipDate) error {
+	result := d.Client.Save(skip)
+	return result.Error
+}
+
+// GetSkipDates returns the days the trip is skipped on in order.
+func (d *Database) GetSkipDates(tripID string) ([]*SkipDate, error) {
+	skips := []*SkipDate{}
+	result := d.Client.Order("date").Find(&skips, SkipDate{TripID: tripID})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return skips, nil
+}
+
+// GetUserSkipDates returns the days any of the user's trips are skipped on in
+// order.
+func (d *Database) GetUserSkipDates(userID string) ([]*SkipDate, error) {
+	skips := []*SkipDate{}
+	result := d.Client.Where("trip_id IN (SELECT id FROM trips WHERE user_id = ?)", userID).Order("date").Find(&skips)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return skips, nil
+}
+
+func (d *Database) RemoveSkipDate(tripID string, date string) error {
+	result := d.Client.Where("trip_id = ? AND date = ?", tripID, date).Delete(&SkipDate{})
+	return result.Error
+}
+
+// ResumeTrips resumes the trips whose pause ended before the given date.
+func (d *Database) ResumeTrips(before string) error {
+	result := d.Client.Model(&Trip{}).
+		Where("paused = ? AND paused_until != '' AND paused_until < ?", true, before).
+		Updates(map[string]any{"paused": false, "paused_until": ""})
+	return result.Error
+}
+
+// PruneExceptions removes the away ranges and skip dates that ended before
+// the given date.
+func (d *Database) PruneExceptions(before string) error {
+	if result := d.Client.Where("end_date < ?", before).Delete(&Away{}); result.Error != nil {
+		return result.Error
+	}
+	result := d.Client.Where("date < ?", before).Delete(&SkipDate{})
+	return result.Error
+}
+
+// SavePlace adds the place, replacing any place of the user with the same
+// alias regardless of case.
+func (d *Database) SavePlace(place *Place) error {
+	return d.Client.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND LOWER(alias) = LOWER(?)", place.UserID, place.Alias).Delete(&Place{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(place).Error
+	})
+}
+
+// GetPlaces returns the places of the user ordered by alias.
+func (d *Database) GetPlaces(userID string) ([]*Place, error) {
+	places := []*Place{}
+	result := d.Client.Order("alias").Find(&places, Place{UserID: userID})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return places, nil
+}
+
+// GetPlace finds a place of the user by its alias, ignoring case.
+func (d *Database) GetPlace(userID string, alias string) (*Place, error) {
+	place := &Place{}
+	result := d.Client.Where("user_id = ? AND LOWER(alias) = LOWER(?)", userID, alias).First(place)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return place, nil
+}
+
+func (d *Database) RemovePlace(userID string, alias string) error {
+	result := d.Client.Where("user_id = ? AND LOWER(alias) = LOWER(?)", userID, alias).Delete(&Place{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// legacyTrip is a trip as it was stored before recurrences, with a column
+// per weekday.
+type legacyTrip struct {
+	ID        string `gorm:"primaryKey"`
+	UserID    string
+	Name      string
+	Time      string
+	Date      string
+	Monday    bool
+	Tuesday   bool
+	Wednesday bool
+	Thursday  bool
+	Friday    bool
+	Saturday  bool
+	Sunday    bool
+}
+
+func (legacyTrip) TableName() string {
+	return "trips"
+}
+
+func TestMigrateWeekdays(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pascal.db")
+	baseline, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open baseline: %v", err)
+	}
+	if err := baseline.AutoMigrate(&legacyTrip{}); err != nil {
+		t.Fatalf("failed to create baseline: %v", err)
+	}
+	trips := []legacyTrip{
+		{ID: "work", Name: "Work", Time: "08:00:00", Monday: true, Tuesday: true, Wednesday: true, Thursday: true, Friday: true},
+		{ID: "gym", Name: "Gym", Time: "18:00:00", Sunday: true, Wednesday: true},
+		{ID: "once", Name: "Once", Time: "12:00:00", Date: "2026-10-24"},
+		{ID: "unset", Name: "Unset", Time: "09:00:00", Monday: true},
+	}
+	if err := baseline.Create(&trips).Error; err != nil {
+		t.Fatalf("failed to add baseline trips: %v", err)
+	}
+	// Rows from before a column was added hold NULL rather than false
+	if err := baseline.Exec("UPDATE trips SET monday = NULL, tuesday = NULL WHERE id = ?", "unset").Error; err != nil {
+		t.Fatalf("failed to clear weekdays: %v", err)
+	}
+	if db, err := baseline.DB(); err == nil {
+		db.Close()
+	}
+
+	want := map[string]Recurrence{
+		"work":  WeeklyOn(time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday),
+		"gym":   WeeklyOn(time.Wednesday, time.Sunday),
+		"once":  {},
+		"unset": {},
+	}
+	// Opening a migrated database again must leave it as it is
+	for range 2 {
+		d, err := NewDatabase(path)
+		if err != nil {
+			t.Fatalf("failed to migrate: %v", err)
+		}
+		for _, column := range weekdayColumns {
+			if d.Client.Migrator().HasColumn(&Trip{}, column) {
+				t.Errorf("column %s is still there", column)
+			}
+		}
+		migrated, err := d.GetAllTrips()
+		if err != nil {
+			t.Fatalf("failed to get trips: %v", err)
+		}
+		if len(migrated) != len(want) {
+			t.Fatalf("got %d trips, want %d", len(migrated), len(want))
+		}
+		for _, trip := range migrated {
+			if trip.Recurrence.String() != want[trip.ID].String() {
+				t.Errorf("trip %s runs %q, want %q", trip.ID, trip.Recurrence, want[trip.ID])
+			}
+		}
+		once, err := d.GetTrip("", "once")
+		if err != nil || once.Date != "2026-10-24" || once.Name != "Once" {
+			t.Errorf("got one-off trip %+v, %v", once, err)
+		}
+		if db, err := d.Client.DB(); err == nil {
+			db.Close()
+		}
+	}
+}
@@ -1,7 +1,12 @@
 package database
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/vincbro/pascal/blaise"
@@ -13,6 +18,30 @@ type User struct {
 	ChannelID string
 	Trips     []Trip
 	Locations []blaise.Location `gorm:"serializer:json"`
+	// Alerts is the default alert profile of the users trips, empty means DefaultAlerts
+	Alerts []blaise.Time `gorm:"serializer:json"`
+	// Vacation pauses every trip of the user until it is turned off
+	Vacation bool
+	// LiveAlerts edits a single message per trip and day instead of posting
+	// every departure alert
+	LiveAlerts bool
+	// TimeZone is the IANA name of the zone the users trips run in, empty
+	// means the zone of the server
+	TimeZone string
+}
+
+// Location returns the time zone of the user, falling back to the zone of the
+// server if none is set or it can't be loaded.
+func (u *User) Location() *time.Location {
+	if u == nil || u.TimeZone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(u.TimeZone)
+	if err != nil {
+		slog.Warn("failed to load time zone of user", "user", u.ID, "zone", u.TimeZone, "error", err)
+		return time.Local
+	}
+	return loc
 }
 
 func (u *User) AddHistory(newLoc blaise.Location) {
@@ -31,6 +60,66 @@ func (u *User) AddHistory(newLoc blaise.Location) {
 	}
 }
 
+// DefaultAlerts is the full countdown used when neither the trip nor the user
+// has an alert profile.
+var DefaultAlerts = []blaise.Time{60 * 60, 30 * 60, 15 * 60, 10 * 60, 5 * 60, 4 * 60, 3 * 60, 2 * 60, 1 * 60}
+
+// AlertPresets are the named alert profiles that can be used instead of a
+// list of minutes.
+var AlertPresets = map[string][]blaise.Time{
+	"full":   DefaultAlerts,
+	"short":  {15 * 60, 10 * 60, 5 * 60, 1 * 60},
+	"single": {10 * 60},
+}
+
+// ParseAlerts parses either a preset name or a comma separated list of
+// minutes (e.g. "30, 10, 5"). "default" returns nil, meaning the profile falls
+// back to the next level.
+func ParseAlerts(input string) ([]blaise.Time, error) {
+	input = strings.ToLower(strings.TrimSpace(input))
+	if input == "default" {
+		return nil, nil
+	}
+	if preset, ok := AlertPresets[input]; ok {
+		return slices.Clone(preset), nil
+	}
+
+	alerts := make([]blaise.Time, 0)
+	for part := range strings.SplitSeq(input, ",") {
+		part = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(part), "min"))
+		if part == "" {
+			continue
+		}
+		minutes, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid alert %q: %w", part, err)
+		}
+		if minutes < 1 || minutes > 24*60 {
+			return nil, fmt.Errorf("alert %d min is out of range", minutes)
+		}
+		alerts = append(alerts, blaise.Time(minutes*60))
+	}
+	if len(alerts) == 0 {
+		return nil, errors.New("no alerts given")
+	}
+
+	// Alerts are fired in order, so keep them sorted from the earliest
+	slices.Sort(alerts)
+	slices.Reverse(alerts)
+	return slices.Compact(alerts), nil
+}
+
+func FormatAlerts(alerts []blaise.Time) string {
+	if len(alerts) == 0 {
+		return "default"
+	}
+	minutes := make([]string, len(alerts))
+	for i, alert := range alerts {
+		minutes[i] = strconv.Itoa(int(alert / 60))
+	}
+	return strings.Join(minutes, ", ") + " min"
+}
+
 type Trip struct {
 	ID        string `grom:"primaryKey"`
 	UserID    string `grom:"index"`
@@ -42,45 +131,114 @@ type Trip struct {
 	Time      string
 	Departure bool
 
-	Monday    bool
-	Tuesday   bool
-	Wednesday bool
-	Thursday  bool
-	Friday    bool
-	Saturday  bool
-	Sunday    bool
+	// Recurrence is the days the trip runs on, stored as an RRULE
+	Recurrence Recurrence
+
+	// Date makes the trip run once on that date (YYYY-MM-DD) instead of by its
+	// recurrence, it is removed once it has arrived
+	Date string
+
+	// Alerts is the seconds before departure to alert at, empty means the users default
+	Alerts []blaise.Time `gorm:"serializer:json"`
+
+	// Paused stops the alerts of the trip until PausedUntil (YYYY-MM-DD)
+	// has passed, or until resumed if it is empty
+	Paused      bool
+	PausedUntil string
 
 	ExpectedItinerary blaise.Itinerary `gorm:"serializer:json"`
 }
 
+// DateLayout is the layout of Trip.Date.
+const DateLayout = "2006-01-02"
+
 func (t Trip) FormatSchedule() string {
-	return fmt.Sprintf("**Monday**: %t\n**Tuesday**: %t\n**Wednesday**: %t\n**Thursday**: %t\n**Friday**: %t\n**Saturday**: %t\n**Sunday**: %t",
-		t.Monday,
-		t.Tuesday,
-		t.Wednesday,
-		t.Thursday,
-		t.Friday,
-		t.Saturday,
-		t.Sunday,
-	)
-}
-
-func (t Trip) ShouldRun(weekday time.Weekday) bool {
-	switch weekday {
-	case time.Sunday:
-		return t.Sunday
-	case time.Monday:
-		return t.Monday
-	case time.Tuesday:
-		return t.Tuesday
-	case time.Wednesday:
-		return t.Wednesday
-	case time.Thursday:
-		return t.Thursday
-	case time.Friday:
-		return t.Friday
-	case time.Saturday:
-		return t.Saturday
-	}
-	return false
+	if t.Date != "" {
+		if date, err := time.Parse(DateLayout, t.Date); err == nil {
+			return fmt.Sprintf("**Once** on %s", date.Format("Monday 2 January 2006"))
+		}
+		return fmt.Sprintf("**Once** on %s", t.Date)
+	}
+	return t.Recurrence.Describe()
+}
+
+// AlertOffsets resolves the alert profile of the trip, falling back to the
+// users default and then DefaultAlerts.
+func (t Trip) AlertOffsets(user *User) []blaise.Time {
+	if len(t.Alerts) > 0 {
+		return t.Alerts
+	}
+	if user != nil && len(user.Alerts) > 0 {
+		return user.Alerts
+	}
+	return DefaultAlerts
+}
+
+// PausedOn reports whether the trip is paused on the day of date.
+func (t Trip) PausedOn(date time.Time) bool {
+	return t.Paused && (t.PausedUntil == "" || date.Format(DateLayout) <= t.PausedUntil)
+}
+
+// FormatPaused describes the pause of the trip, empty if it isn't paused.
+func (t Trip) FormatPaused() string {
+	if !t.Paused {
+		return ""
+	}
+	if date, err := time.Parse(DateLayout, t.PausedUntil); err == nil {
+		return fmt.Sprintf("⏸️ **Paused** until %s", date.Format("Monday 2 January"))
+	}
+	return "⏸️ **Paused** until resumed"
+}
+
+// RunsOn reports whether the trip runs on the day of date.
+func (t Trip) RunsOn(date time.Time) bool {
+	if t.Date != "" {
+		return date.Format(DateLayout) == t.Date
+	}
+	return t.ShouldRun(date)
+}
+
+// ShouldRun reports whether the recurrence of the trip includes the day of
+// date.
+func (t Trip) ShouldRun(date time.Time) bool {
+	return t.Recurrence.Occurs(date)
+}
+
+// AlertState is the delivered alerts and mute state of a single trip on a
+// single service date.
+type AlertState struct {
+	TripID       string `gorm:"primaryKey"`
+	ServiceDate  string `gorm:"primaryKey"`
+	AlertHistory []bool `gorm:"serializer:json"`
+	Muted        bool
+	// MessageID is the live alert message of the occurrence, if any
+	MessageID string
+}
+
+// Away is a range of days, inclusive, where none of the users trips run.
+type Away struct {
+	ID        uint   `gorm:"primaryKey"`
+	UserID    string `gorm:"index"`
+	StartDate string
+	EndDate   string
+	Reason    string
+}
+
+// Covers reports whether the day of date is within the range.
+func (a Away) Covers(date time.Time) bool {
+	day := date.Format(DateLayout)
+	return a.StartDate <= day && day <= a.EndDate
+}
+
+// SkipDate is a single day a trip does not run on.
+type SkipDate struct {
+	TripID string `gorm:"primaryKey"`
+	Date   string `gorm:"primaryKey"`
+}
+
+// Place is a location the user has given a name, such as Home or Work.
+type Place struct {
+	UserID   string          `gorm:"primaryKey"`
+	Alias    string          `gorm:"primaryKey"`
+	Location blaise.Location `gorm:"serializer:json"`
 }
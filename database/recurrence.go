@@ -0,0 +1,522 @@
+package database
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+)
+
+// RecurDay is a BYDAY entry. N picks a single occurrence within the month for
+// monthly rules, 1 is the first and -1 the last, 0 means every such weekday.
+type RecurDay struct {
+	N       int
+	Weekday time.Weekday
+}
+
+// Recurrence is the subset of an RFC 5545 RRULE a trip can repeat by: FREQ
+// (DAILY, WEEKLY or MONTHLY), INTERVAL, BYDAY, BYMONTHDAY and UNTIL. Start is
+// the DTSTART the interval counts from. The zero value never occurs.
+type Recurrence struct {
+	Freq       Frequency
+	Interval   int
+	ByDay      []RecurDay
+	ByMonthDay []int
+	// Start is the first day (YYYY-MM-DD), empty means no limit
+	Start string
+	// Until is the last day (YYYY-MM-DD), empty means no limit
+	Until string
+}
+
+var weekdayCodes = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// WeeklyOn is a recurrence every week on the given days.
+func WeeklyOn(days ...time.Weekday) Recurrence {
+	if len(days) == 0 {
+		return Recurrence{}
+	}
+	r := Recurrence{Freq: Weekly, Interval: 1}
+	for _, day := range days {
+		r.ByDay = append(r.ByDay, RecurDay{Weekday: day})
+	}
+	r.sortDays()
+	return r
+}
+
+// ParseRecurrence parses an RRULE such as "FREQ=WEEKLY;INTERVAL=2;BYDAY=FR",
+// optionally prefixed with "RRULE:" and preceded by a "DTSTART:20250103" line.
+// The shorthands "daily", "weekdays" and "weekends" are accepted as well.
+func ParseRecurrence(input string) (Recurrence, error) {
+	input = strings.TrimSpace(input)
+	switch strings.ToLower(input) {
+	case "daily":
+		return Recurrence{Freq: Daily, Interval: 1}, nil
+	case "weekdays":
+		return WeeklyOn(time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday), nil
+	case "weekends":
+		return WeeklyOn(time.Saturday, time.Sunday), nil
+	}
+
+	r := Recurrence{Interval: 1}
+	for line := range strings.FieldsSeq(input) {
+		upper := strings.ToUpper(line)
+		if strings.HasPrefix(upper, "DTSTART") {
+			_, value, ok := strings.Cut(line, ":")
+			if !ok {
+				return Recurrence{}, fmt.Errorf("invalid DTSTART %q", line)
+			}
+			start, err := parseRuleDate(value)
+			if err != nil {
+				return Recurrence{}, fmt.Errorf("invalid DTSTART %q: %w", value, err)
+			}
+			r.Start = start
+			continue
+		}
+		if err := r.parseRule(strings.TrimPrefix(upper, "RRULE:")); err != nil {
+			return Recurrence{}, err
+		}
+	}
+	if err := r.validate(); err != nil {
+		return Recurrence{}, err
+	}
+	r.sortDays()
+	return r, nil
+}
+
+func (r *Recurrence) parseRule(rule string) error {
+	for part := range strings.SplitSeq(rule, ";") {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return fmt.Errorf("invalid rule part %q", part)
+		}
+		switch key {
+		case "FREQ":
+			r.Freq = Frequency(value)
+		case "INTERVAL":
+			interval, err := strconv.Atoi(value)
+			if err != nil || interval < 1 {
+				return fmt.Errorf("invalid INTERVAL %q", value)
+			}
+			r.Interval = interval
+		case "BYDAY":
+			for code := range strings.SplitSeq(value, ",") {
+				day, err := parseRecurDay(code)
+				if err != nil {
+					return err
+				}
+				r.ByDay = append(r.ByDay, day)
+			}
+		case "BYMONTHDAY":
+			for code := range strings.SplitSeq(value, ",") {
+				day, err := strconv.Atoi(code)
+				if err != nil || day == 0 || day < -31 || day > 31 {
+					return fmt.Errorf("invalid BYMONTHDAY %q", code)
+				}
+				r.ByMonthDay = append(r.ByMonthDay, day)
+			}
+		case "UNTIL":
+			until, err := parseRuleDate(value)
+			if err != nil {
+				return fmt.Errorf("invalid UNTIL %q: %w", value, err)
+			}
+			r.Until = until
+		default:
+			return fmt.Errorf("%s is not supported", key)
+		}
+	}
+	return nil
+}
+
+func parseRecurDay(code string) (RecurDay, error) {
+	if len(code) < 2 {
+		return RecurDay{}, fmt.Errorf("invalid BYDAY %q", code)
+	}
+	weekday := slices.Index(weekdayCodes, code[len(code)-2:])
+	if weekday < 0 {
+		return RecurDay{}, fmt.Errorf("invalid BYDAY %q", code)
+	}
+	day := RecurDay{Weekday: time.Weekday(weekday)}
+	if prefix := code[:len(code)-2]; prefix != "" {
+		n, err := strconv.Atoi(prefix)
+		if err != nil || n == 0 || n < -5 || n > 5 {
+			return RecurDay{}, fmt.Errorf("invalid BYDAY %q", code)
+		}
+		day.N = n
+	}
+	return day, nil
+}
+
+// parseRuleDate accepts both the RFC 5545 DATE and DATE-TIME forms and
+// YYYY-MM-DD, the time of day is ignored.
+func parseRuleDate(value string) (string, error) {
+	if date, err := time.Parse(DateLayout, value); err == nil {
+		return date.Format(DateLayout), nil
+	}
+	if len(value) < 8 {
+		return "", errors.New("not a date")
+	}
+	date, err := time.Parse("20060102", value[:8])
+	if err != nil {
+		return "", err
+	}
+	return date.Format(DateLayout), nil
+}
+
+func (r Recurrence) validate() error {
+	switch r.Freq {
+	case Daily, Weekly, Monthly:
+	case "":
+		return errors.New("FREQ is required")
+	default:
+		return fmt.Errorf("FREQ=%s is not supported, use DAILY, WEEKLY or MONTHLY", r.Freq)
+	}
+	if len(r.ByMonthDay) > 0 && r.Freq != Monthly {
+		return errors.New("BYMONTHDAY can only be used with FREQ=MONTHLY")
+	}
+	if r.Freq != Monthly {
+		for _, day := range r.ByDay {
+			if day.N != 0 {
+				return errors.New("numbered BYDAY can only be used with FREQ=MONTHLY")
+			}
+		}
+	}
+	if r.Start != "" && r.Until != "" && r.Until < r.Start {
+		return errors.New("UNTIL is before DTSTART")
+	}
+	return nil
+}
+
+func (r *Recurrence) sortDays() {
+	// Weeks start on Monday
+	order := func(day time.Weekday) int { return (int(day) + 6) % 7 }
+	slices.SortFunc(r.ByDay, func(a, b RecurDay) int {
+		if a.Weekday != b.Weekday {
+			return order(a.Weekday) - order(b.Weekday)
+		}
+		return a.N - b.N
+	})
+	r.ByDay = slices.Compact(r.ByDay)
+	// Days counted from the end of the month go last
+	monthOrder := func(day int) int {
+		if day < 0 {
+			return 100 + day
+		}
+		return day
+	}
+	slices.SortFunc(r.ByMonthDay, func(a, b int) int { return monthOrder(a) - monthOrder(b) })
+	r.ByMonthDay = slices.Compact(r.ByMonthDay)
+}
+
+// StartingOn anchors the interval of a recurrence without a DTSTART to the
+// given day (YYYY-MM-DD).
+func (r Recurrence) StartingOn(day string) Recurrence {
+	if !r.IsZero() && r.Start == "" {
+		r.Start = day
+	}
+	return r
+}
+
+// IsZero reports whether the recurrence never occurs.
+func (r Recurrence) IsZero() bool {
+	return r.Freq == ""
+}
+
+// Rule formats the RRULE without the DTSTART.
+func (r Recurrence) Rule() string {
+	if r.IsZero() {
+		return ""
+	}
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, day := range r.ByDay {
+			days[i] = weekdayCodes[day.Weekday]
+			if day.N != 0 {
+				days[i] = strconv.Itoa(day.N) + days[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, day := range r.ByMonthDay {
+			days[i] = strconv.Itoa(day)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if r.Until != "" {
+		parts = append(parts, "UNTIL="+strings.ReplaceAll(r.Until, "-", ""))
+	}
+	return strings.Join(parts, ";")
+}
+
+// String formats the recurrence the way ParseRecurrence reads it.
+func (r Recurrence) String() string {
+	if r.IsZero() || r.Start == "" {
+		return r.Rule()
+	}
+	return "DTSTART:" + strings.ReplaceAll(r.Start, "-", "") + "\nRRULE:" + r.Rule()
+}
+
+func (r Recurrence) GormDataType() string {
+	return "string"
+}
+
+func (r Recurrence) Value() (driver.Value, error) {
+	return r.String(), nil
+}
+
+func (r *Recurrence) Scan(value any) error {
+	var text string
+	switch v := value.(type) {
+	case nil:
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	default:
+		return fmt.Errorf("cannot scan %T into a recurrence", value)
+	}
+	if strings.TrimSpace(text) == "" {
+		*r = Recurrence{}
+		return nil
+	}
+	parsed, err := ParseRecurrence(text)
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// civil drops the time and location of date so days can be counted.
+func civil(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Occurs reports whether the recurrence includes the day of date.
+func (r Recurrence) Occurs(date time.Time) bool {
+	if r.IsZero() {
+		return false
+	}
+	day := date.Format(DateLayout)
+	if (r.Start != "" && day < r.Start) || (r.Until != "" && day > r.Until) {
+		return false
+	}
+	var start time.Time
+	if r.Start != "" {
+		start, _ = time.Parse(DateLayout, r.Start)
+	}
+	interval := max(r.Interval, 1)
+	date = civil(date)
+
+	switch r.Freq {
+	case Daily:
+		if !start.IsZero() && int(date.Sub(start).Hours()/24)%interval != 0 {
+			return false
+		}
+		return len(r.ByDay) == 0 || r.hasWeekday(date.Weekday())
+	case Weekly:
+		if !start.IsZero() {
+			weeks := int(weekStart(date).Sub(weekStart(start)).Hours() / (24 * 7))
+			if weeks%interval != 0 {
+				return false
+			}
+		}
+		if len(r.ByDay) == 0 {
+			return !start.IsZero() && date.Weekday() == start.Weekday()
+		}
+		return r.hasWeekday(date.Weekday())
+	case Monthly:
+		if !start.IsZero() {
+			months := (date.Year()-start.Year())*12 + int(date.Month()) - int(start.Month())
+			if months%interval != 0 {
+				return false
+			}
+		}
+		if len(r.ByMonthDay) == 0 && len(r.ByDay) == 0 {
+			return !start.IsZero() && date.Day() == start.Day()
+		}
+		// With both, BYDAY limits the days BYMONTHDAY picks as in RFC 5545,
+		// e.g. BYDAY=MO;BYMONTHDAY=13 is only a Monday the 13th
+		return (len(r.ByMonthDay) == 0 || r.onMonthDay(date)) && (len(r.ByDay) == 0 || r.onNthWeekday(date))
+	}
+	return false
+}
+
+func (r Recurrence) onMonthDay(date time.Time) bool {
+	last := time.Date(date.Year(), date.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	for _, n := range r.ByMonthDay {
+		if n == date.Day() || last+n+1 == date.Day() {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Recurrence) onNthWeekday(date time.Time) bool {
+	last := time.Date(date.Year(), date.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	nth := (date.Day()-1)/7 + 1
+	nthLast := -((last-date.Day())/7 + 1)
+	for _, day := range r.ByDay {
+		if day.Weekday == date.Weekday() && (day.N == 0 || day.N == nth || day.N == nthLast) {
+			return true
+		}
+	}
+	return false
+}
+
+func weekStart(date time.Time) time.Time {
+	return date.AddDate(0, 0, -((int(date.Weekday()) + 6) % 7))
+}
+
+func (r Recurrence) hasWeekday(weekday time.Weekday) bool {
+	for _, day := range r.ByDay {
+		if day.Weekday == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+// Weekdays returns the days of a rule that only picks days of the week, such
+// as the ones made by WeeklyOn. ok is false for anything more complex.
+func (r Recurrence) Weekdays() ([]time.Weekday, bool) {
+	if r.IsZero() {
+		return nil, true
+	}
+	if r.Freq == Monthly || r.Interval > 1 {
+		return nil, false
+	}
+	if len(r.ByDay) == 0 {
+		if r.Freq == Daily {
+			return []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday}, true
+		}
+		return nil, false
+	}
+	days := make([]time.Weekday, len(r.ByDay))
+	for i, day := range r.ByDay {
+		days[i] = day.Weekday
+	}
+	return days, true
+}
+
+func ordinal(n int) string {
+	names := []string{"first", "second", "third", "fourth", "fifth"}
+	switch {
+	case n == -1:
+		return "last"
+	case n < 0 && -n <= len(names):
+		return names[-n-1] + " to last"
+	case n > 0 && n <= len(names):
+		return names[n-1]
+	default:
+		return strconv.Itoa(n)
+	}
+}
+
+// Describe formats the recurrence for people, e.g. "Every other week on
+// **Friday**".
+func (r Recurrence) Describe() string {
+	if r.IsZero() {
+		return "**Never**"
+	}
+	every := func(unit string) string {
+		switch r.Interval {
+		case 0, 1:
+			return "every **" + unit + "**"
+		case 2:
+			return "every other **" + unit + "**"
+		default:
+			return fmt.Sprintf("every **%d %ss**", r.Interval, unit)
+		}
+	}
+
+	var text string
+	switch r.Freq {
+	case Daily, Weekly:
+		days, _ := r.Weekdays()
+		switch {
+		case len(days) == 7 || (r.Freq == Daily && len(r.ByDay) == 0):
+			text = every("day")
+		case r.Freq == Weekly && len(r.ByDay) == 0:
+			text = every("week")
+		case r.Interval <= 1:
+			text = "every " + r.describeDays()
+		case r.Freq == Daily:
+			text = every("day") + " on " + r.describeDays()
+		default:
+			text = every("week") + " on " + r.describeDays()
+		}
+	case Monthly:
+		switch {
+		case len(r.ByMonthDay) > 0:
+			days := make([]string, len(r.ByMonthDay))
+			for i, day := range r.ByMonthDay {
+				days[i] = fmt.Sprintf("day %d", day)
+				if day < 0 {
+					days[i] = fmt.Sprintf("the %s day", ordinal(day))
+				}
+			}
+			text = fmt.Sprintf("on %s of %s", joinBold(days), every("month"))
+			if len(r.ByDay) > 0 {
+				text += " when it is a " + r.describeDays()
+			}
+		case len(r.ByDay) > 0:
+			text = fmt.Sprintf("on %s of %s", r.describeDays(), every("month"))
+		default:
+			text = every("month")
+		}
+	}
+	text = strings.ToUpper(text[:1]) + text[1:]
+	if r.Until != "" {
+		if until, err := time.Parse(DateLayout, r.Until); err == nil {
+			text += fmt.Sprintf(" until **%s**", until.Format("2 January 2006"))
+		}
+	}
+	return text
+}
+
+func (r Recurrence) describeDays() string {
+	names := make([]string, len(r.ByDay))
+	for i, day := range r.ByDay {
+		names[i] = day.Weekday.String()
+		if day.N != 0 {
+			names[i] = "the " + ordinal(day.N) + " " + names[i]
+		}
+	}
+	days, _ := r.Weekdays()
+	if slices.Equal(days, []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}) {
+		return "**weekday**"
+	}
+	return joinBold(names)
+}
+
+// joinBold formats a list as "**a**, **b** and **c**".
+func joinBold(items []string) string {
+	bold := make([]string, len(items))
+	for i, item := range items {
+		bold[i] = "**" + item + "**"
+	}
+	if len(bold) == 1 {
+		return bold[0]
+	}
+	return strings.Join(bold[:len(bold)-1], ", ") + " and " + bold[len(bold)-1]
+}
@@ -0,0 +1,161 @@
+package database
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRecurrence(t *testing.T) {
+	tests := []struct {
+		input string
+		// rule is what String gives back, empty for an input that must fail
+		rule string
+	}{
+		{"daily", "FREQ=DAILY"},
+		{"Weekdays", "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR"},
+		{"weekends", "FREQ=WEEKLY;BYDAY=SA,SU"},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=FR", "FREQ=WEEKLY;INTERVAL=2;BYDAY=FR"},
+		{"RRULE:freq=weekly;byday=fr,mo,mo", "FREQ=WEEKLY;BYDAY=MO,FR"},
+		{"FREQ=MONTHLY;BYDAY=-1FR,1MO", "FREQ=MONTHLY;BYDAY=1MO,-1FR"},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1,15,1", "FREQ=MONTHLY;BYMONTHDAY=1,15,-1"},
+		{"FREQ=MONTHLY;BYDAY=MO;BYMONTHDAY=13", "FREQ=MONTHLY;BYDAY=MO;BYMONTHDAY=13"},
+		{"FREQ=DAILY;UNTIL=20261218T230000Z", "FREQ=DAILY;UNTIL=20261218"},
+		{"DTSTART:20260102\nRRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=FR", "DTSTART:20260102\nRRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=FR"},
+		{"DTSTART;VALUE=DATE:2026-01-02 FREQ=MONTHLY", "DTSTART:20260102\nRRULE:FREQ=MONTHLY"},
+
+		{"", ""},
+		{"sometimes", ""},
+		{"FREQ=YEARLY", ""},
+		{"INTERVAL=2", ""},
+		{"FREQ=DAILY;INTERVAL=0", ""},
+		{"FREQ=WEEKLY;BYDAY=XX", ""},
+		{"FREQ=WEEKLY;BYDAY=1MO", ""},
+		{"FREQ=MONTHLY;BYDAY=6MO", ""},
+		{"FREQ=WEEKLY;BYMONTHDAY=1", ""},
+		{"FREQ=MONTHLY;BYMONTHDAY=32", ""},
+		{"FREQ=MONTHLY;BYMONTHDAY=0", ""},
+		{"FREQ=DAILY;COUNT=3", ""},
+		{"FREQ=DAILY;UNTIL=soon", ""},
+		{"DTSTART:20261201 FREQ=DAILY;UNTIL=20261101", ""},
+		{"DTSTART 20260102 FREQ=DAILY", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			r, err := ParseRecurrence(tt.input)
+			if tt.rule == "" {
+				if err == nil {
+					t.Fatalf("parsed as %q, want an error", r)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to parse: %v", err)
+			}
+			if r.String() != tt.rule {
+				t.Errorf("got %q, want %q", r, tt.rule)
+			}
+			// What is stored must read back the same
+			again, err := ParseRecurrence(r.String())
+			if err != nil || again.String() != r.String() {
+				t.Errorf("%q reads back as %q, %v", r, again, err)
+			}
+		})
+	}
+}
+
+func TestOccurs(t *testing.T) {
+	mustParse := func(rule string) Recurrence {
+		r, err := ParseRecurrence(rule)
+		if err != nil {
+			t.Fatalf("failed to parse %q: %v", rule, err)
+		}
+		return r
+	}
+	tests := []struct {
+		rule  string
+		dates map[string]bool
+	}{
+		{"daily", map[string]bool{"2026-10-19": true, "2026-10-31": true}},
+		{"weekdays", map[string]bool{"2026-10-23": true, "2026-10-31": false, "2026-10-19": true}},
+		{
+			// Every other Friday from 2 January
+			"DTSTART:20260102\nRRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=FR",
+			map[string]bool{"2025-12-19": false, "2026-01-02": true, "2026-01-09": false, "2026-01-16": true, "2026-10-23": true, "2026-10-30": false},
+		},
+		{
+			// A week with no days runs on the weekday it started
+			"DTSTART:20261019\nRRULE:FREQ=WEEKLY",
+			map[string]bool{"2026-10-19": true, "2026-10-26": true, "2026-10-23": false},
+		},
+		{
+			"DTSTART:20261019\nRRULE:FREQ=DAILY;INTERVAL=3",
+			map[string]bool{"2026-10-19": true, "2026-10-20": false, "2026-10-22": true, "2026-10-18": false},
+		},
+		{"FREQ=MONTHLY;BYDAY=-1FR", map[string]bool{"2026-10-30": true, "2026-10-23": false, "2026-02-27": true}},
+		{"FREQ=MONTHLY;BYDAY=1MO", map[string]bool{"2026-11-02": true, "2026-02-02": true, "2026-10-19": false}},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1", map[string]bool{"2026-10-31": true, "2026-02-28": true, "2026-03-31": true, "2026-10-30": false}},
+		{
+			// Only a Monday the 13th, not every 13th or every Monday
+			"FREQ=MONTHLY;BYDAY=MO;BYMONTHDAY=13",
+			map[string]bool{"2026-04-13": true, "2026-07-13": true, "2026-10-13": false, "2026-10-19": false},
+		},
+		{
+			"DTSTART:20260113\nRRULE:FREQ=MONTHLY;INTERVAL=3",
+			map[string]bool{"2026-01-13": true, "2026-04-13": true, "2026-02-13": false, "2026-04-14": false},
+		},
+		{"FREQ=DAILY;UNTIL=20261020", map[string]bool{"2026-10-20": true, "2026-10-21": false}},
+		{"", map[string]bool{"2026-10-19": false}},
+	}
+	for _, tt := range tests {
+		var r Recurrence
+		if tt.rule != "" {
+			r = mustParse(tt.rule)
+		}
+		for date, want := range tt.dates {
+			// The time of day and the zone never move the date
+			day, err := time.ParseInLocation(DateLayout+" 15:04", date+" 23:30", time.FixedZone("UTC+14", 14*3600))
+			if err != nil {
+				t.Fatalf("bad date %s: %v", date, err)
+			}
+			if got := r.Occurs(day); got != want {
+				t.Errorf("%q on %s (%s) is %v, want %v", tt.rule, date, day.Weekday(), got, want)
+			}
+		}
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		rule string
+		want string
+	}{
+		{"daily", "Every day"},
+		{"weekdays", "Every weekday"},
+		{"weekends", "Every Saturday and Sunday"},
+		{"FREQ=WEEKLY;BYDAY=MO,WE,FR", "Every Monday, Wednesday and Friday"},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=FR", "Every other week on Friday"},
+		{"FREQ=WEEKLY;INTERVAL=3", "Every 3 weeks"},
+		{"FREQ=DAILY;INTERVAL=2;BYDAY=MO", "Every other day on Monday"},
+		{"FREQ=MONTHLY;BYDAY=-1FR", "On the last Friday of every month"},
+		{"FREQ=MONTHLY;INTERVAL=2;BYDAY=1MO", "On the first Monday of every other month"},
+		{"FREQ=MONTHLY;BYMONTHDAY=1,-2", "On day 1 and the second to last day of every month"},
+		{"FREQ=MONTHLY;BYDAY=MO;BYMONTHDAY=13", "On day 13 of every month when it is a Monday"},
+		{"FREQ=DAILY;UNTIL=20261218", "Every day until 18 December 2026"},
+		{"", "Never"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			var r Recurrence
+			if tt.rule != "" {
+				var err error
+				if r, err = ParseRecurrence(tt.rule); err != nil {
+					t.Fatalf("failed to parse: %v", err)
+				}
+			}
+			if got := strings.ReplaceAll(r.Describe(), "**", ""); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
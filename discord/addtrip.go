@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -16,6 +17,9 @@ import (
 	"github.com/vincbro/pascal/state"
 )
 
+// routeOptions is how many alternative itineraries /add offers to pick from.
+const routeOptions = 3
+
 func CreateAddTripCommand() Command {
 	return Command{
 		Definition: &discordgo.ApplicationCommand{
@@ -94,6 +98,24 @@ func CreateAddTripCommand() Command {
 					Description: "Should the trip run on Sunday",
 					Type:        discordgo.ApplicationCommandOptionBoolean,
 				},
+				{
+					Name:         "alerts",
+					Description:  "When to alert you, a preset or minutes before departure (e.g. 30, 10, 5)",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Autocomplete: true,
+				},
+				{
+					Name:         "recurrence",
+					Description:  "Repeat by a rule instead of the days, e.g. FREQ=WEEKLY;INTERVAL=2;BYDAY=FR",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Autocomplete: true,
+				},
+				{
+					Name:         "date",
+					Description:  "Run the trip once on this date (YYYY-MM-DD) instead of repeating it",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Autocomplete: true,
+				},
 			},
 		},
 		Handler:      addTripHandler,
@@ -108,30 +130,34 @@ func addTripHandler(s *discordgo.Session, i *discordgo.InteractionCreate, state
 	}
 	opts := ParseOptions(i.ApplicationCommandData().Options)
 
-	hasDay := func(day string) bool {
-		val, ok := opts[day]
-		if ok {
-			return val.BoolValue()
-		} else {
-			return true
-		}
-	}
-
 	name := opts["name"].StringValue()
-	from := opts["from"].StringValue()
-	to := opts["to"].StringValue()
+	from := resolvePlace(state, user, opts["from"].StringValue())
+	to := resolvePlace(state, user, opts["to"].StringValue())
 	time := opts["time"].StringValue()
 	departure := opts["type"].StringValue() == "depart"
 
-	monday := hasDay("monday")
-	tuesday := hasDay("tuesday")
-	wednesday := hasDay("wednesday")
-	thursday := hasDay("thursday")
-	friday := hasDay("friday")
-	saturday := hasDay("saturday")
-	sunday := hasDay("sunday")
+	recurrence, err := parseRecurrenceOptions(opts, userNow(state, user))
+	if err != nil {
+		return err
+	}
+
+	var alerts []blaise.Time
+	if value, ok := opts["alerts"]; ok {
+		alerts, err = database.ParseAlerts(value.StringValue())
+		if err != nil {
+			return err
+		}
+	}
+
+	date := ""
+	if value, ok := opts["date"]; ok {
+		date, err = parseTripDate(value.StringValue(), userNow(state, user))
+		if err != nil {
+			return err
+		}
+	}
 
-	itenirary, err := state.BClient.Routing(context.Background(), from, to, time, departure)
+	iteniraries, err := state.BClient.Routing(context.Background(), from, to, time, departure, routeOptions)
 	if err != nil {
 		return err
 	}
@@ -140,42 +166,160 @@ func addTripHandler(s *discordgo.Session, i *discordgo.InteractionCreate, state
 		ID:        uuid.New().String(),
 		UserID:    user.ID,
 		Name:      name,
-		From:      itenirary.From.Name,
-		FromID:    itenirary.From.ID,
-		To:        itenirary.To.Name,
-		ToID:      itenirary.To.ID,
 		Time:      time,
 		Departure: departure,
 
-		Monday:    monday,
-		Tuesday:   tuesday,
-		Wednesday: wednesday,
-		Thursday:  thursday,
-		Friday:    friday,
-		Saturday:  saturday,
-		Sunday:    sunday,
+		Recurrence: recurrence,
+		Date:       date,
+		Alerts:     alerts,
+	}
+
+	if len(iteniraries) == 1 {
+		embed, err := saveTrip(state, user, &trip, iteniraries[0])
+		if err != nil {
+			return err
+		}
+		return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Embeds: []*discordgo.MessageEmbed{embed},
+			},
+		})
+	}
+
+	// Let the user pick which of the alternatives to watch before saving
+	draftID := drafts.put(tripDraft{trip: trip, options: iteniraries}, state.Clock.Now())
+	fields := make([]*discordgo.MessageEmbedField, 0, len(iteniraries))
+	menuOptions := make([]discordgo.SelectMenuOption, 0, len(iteniraries))
+	for n, itenirary := range iteniraries {
+		label := fmt.Sprintf("%s ➔ %s (%d min)",
+			itenirary.DepartureTime.ToHMSString(),
+			itenirary.ArrivalTime.ToHMSString(),
+			(itenirary.ArrivalTime-itenirary.DepartureTime)/60,
+		)
+		transfers := fmt.Sprintf("%d transfers", itenirary.Transfers())
+		if itenirary.Transfers() == 1 {
+			transfers = "1 transfer"
+		}
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:  fmt.Sprintf("Option %d • %s", n+1, label),
+			Value: fmt.Sprintf("%s\n%s", itenirary.Summary(), transfers),
+		})
+		menuOptions = append(menuOptions, discordgo.SelectMenuOption{
+			Label:       fmt.Sprintf("Option %d • %s", n+1, label),
+			Description: fmt.Sprintf("%s, %s", transfers, strings.Join(itenirary.Lines(), " › ")),
+			Value:       strconv.Itoa(n),
+		})
+	}
 
-		ExpectedItinerary: itenirary,
+	embed := &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("🧭 Pick a route: %s", name),
+		Description: fmt.Sprintf("I found %d ways to make this trip, pick the one you want me to watch.", len(iteniraries)),
+		Color:       0x5865F2,
+		Fields:      fields,
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: "Pascal • Watching your commute",
+		},
 	}
 
-	if err = state.DB.AddTrip(&trip); err != nil {
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{embed},
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.SelectMenu{
+							CustomID:    ComponentID("add_pick", draftID),
+							Placeholder: "Choose a route",
+							Options:     menuOptions,
+						},
+					},
+				},
+			},
+		},
+	})
+
+	return err
+}
+
+// addTripPickHandler saves the draft trip with the itinerary the user picked
+// from the select menu.
+func addTripPickHandler(s *discordgo.Session, i *discordgo.InteractionCreate, state *state.State, args []string) error {
+	user, err := GetUser(i.User, i.ChannelID, state)
+	if err != nil {
 		return err
 	}
 
+	draft, ok := drafts.get(args[0], state.Clock.Now())
+	if !ok || draft.trip.UserID != user.ID {
+		return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: &discordgo.InteractionResponseData{
+				Content:    "⌛ This choice has expired, run /add again.",
+				Embeds:     []*discordgo.MessageEmbed{},
+				Components: []discordgo.MessageComponent{},
+			},
+		})
+	}
+
+	values := i.MessageComponentData().Values
+	if len(values) == 0 {
+		return fmt.Errorf("no route picked")
+	}
+	n, err := strconv.Atoi(values[0])
+	if err != nil || n < 0 || n >= len(draft.options) {
+		return fmt.Errorf("invalid route option %q", values[0])
+	}
+
+	embed, err := saveTrip(state, user, &draft.trip, draft.options[n])
+	if err != nil {
+		return err
+	}
+	drafts.take(args[0], state.Clock.Now())
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     []*discordgo.MessageEmbed{embed},
+			Components: []discordgo.MessageComponent{},
+		},
+	})
+}
+
+// saveTrip stores trip with itenirary as its expected itinerary, schedules it
+// and returns the confirmation embed.
+func saveTrip(state *state.State, user *database.User, trip *database.Trip, itenirary blaise.Itinerary) (*discordgo.MessageEmbed, error) {
+	trip.From = itenirary.From.Name
+	trip.FromID = itenirary.From.ID
+	trip.To = itenirary.To.Name
+	trip.ToID = itenirary.To.ID
+	trip.ExpectedItinerary = itenirary
+
+	// A dated trip that has left would be removed right after saving it
+	if state.HasDeparted(trip, user) {
+		return nil, errDeparted
+	}
+	if err := state.DB.AddTrip(trip); err != nil {
+		return nil, err
+	}
+	state.ScheduleTrip(trip)
+
 	user.AddHistory(itenirary.From)
 	user.AddHistory(itenirary.To)
-	if err = state.DB.UpdateUser(user); err != nil {
-		return err
+	if err := state.DB.UpdateUser(user); err != nil {
+		return nil, err
 	}
 
 	scheduleType := "Depart at"
-	if !departure {
+	if !trip.Departure {
 		scheduleType = "Arrive by"
 	}
+	if trip.Date != "" {
+		scheduleType = fmt.Sprintf("%s\n%s", trip.FormatSchedule(), scheduleType)
+	}
 
-	// 4. Create the Embed
 	embed := &discordgo.MessageEmbed{
-		Title:       fmt.Sprintf("✅ Saved: %s", name),
+		Title:       fmt.Sprintf("✅ Saved: %s", trip.Name),
 		Description: "I've added this trip to my database. I'll alert you before you need to leave.",
 		Color:       0x57F287,
 		Fields: []*discordgo.MessageEmbedField{
@@ -186,7 +330,7 @@ func addTripHandler(s *discordgo.Session, i *discordgo.InteractionCreate, state
 			},
 			{
 				Name:   "Schedule",
-				Value:  fmt.Sprintf("%s **%s**", scheduleType, time),
+				Value:  fmt.Sprintf("%s **%s**", scheduleType, trip.Time),
 				Inline: true,
 			},
 			{
@@ -198,20 +342,17 @@ func addTripHandler(s *discordgo.Session, i *discordgo.InteractionCreate, state
 				),
 				Inline: false,
 			},
+			{
+				Name:   "Alerts",
+				Value:  database.FormatAlerts(trip.AlertOffsets(user)),
+				Inline: false,
+			},
 		},
 		Footer: &discordgo.MessageEmbedFooter{
 			Text: "Pascal • Watching your commute",
 		},
 	}
-
-	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseChannelMessageWithSource,
-		Data: &discordgo.InteractionResponseData{
-			Embeds: []*discordgo.MessageEmbed{embed},
-		},
-	})
-
-	return err
+	return embed, nil
 }
 
 func addTripAutocomplete(s *discordgo.Session, i *discordgo.InteractionCreate, state *state.State) error {
@@ -229,37 +370,38 @@ func addTripAutocomplete(s *discordgo.Session, i *discordgo.InteractionCreate, s
 		}
 		switch option.Name {
 		case "from", "to":
-			input := option.StringValue()
-			var results []blaise.Location
-			if len(input) == 0 {
-				results = user.Locations
-			} else {
-				results, err = state.BClient.SearchAreas(context.Background(), option.StringValue(), 10)
-				if err != nil {
-					fmt.Println("error failed to search for area", err)
-					return err
-				}
-			}
-			for _, area := range results {
-				choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
-					Name:  area.Name,
-					Value: area.ID,
-				})
+			choices, err = locationChoices(state, user, option.StringValue())
+			if err != nil {
+				return err
 			}
 		case "time":
 			slog.Debug("Asking for time", "q", option.StringValue())
-			for _, choice := range timeSuggestions(option.StringValue()) {
+			for _, choice := range timeSuggestions(userNow(state, user), option.StringValue()) {
 				slog.Debug("Got time suggestion", "time", choice)
 				choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
 					Name:  choice.Format("15:04") + ":00",
 					Value: choice.Format("15:04") + ":00",
 				})
 			}
+		case "alerts":
+			choices = append(choices, alertChoices(option.StringValue())...)
+		case "recurrence":
+			choices = append(choices, recurrenceChoices(option.StringValue())...)
+		case "date":
+			choices = append(choices, dateChoices(userNow(state, user), option.StringValue())...)
+			if strings.HasPrefix("none", strings.ToLower(option.StringValue())) {
+				choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+					Name:  "No date, run every week",
+					Value: "none",
+				})
+			}
 		}
 	}
 
+	// Discord allows at most 25 choices, the last is the header
+	choices = choices[:min(len(choices), 24)]
 	choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
-		Name:  fmt.Sprintf("🕒 Suggestions for %s", time.Now().Format("15:04")),
+		Name:  fmt.Sprintf("🕒 Suggestions for %s", userNow(state, user).Format("15:04")),
 		Value: "REFRESH_HEADER",
 	})
 
@@ -273,8 +415,7 @@ func addTripAutocomplete(s *discordgo.Session, i *discordgo.InteractionCreate, s
 	return err
 }
 
-func timeSuggestions(input string) []time.Time {
-	now := time.Now()
+func timeSuggestions(now time.Time, input string) []time.Time {
 	y, m, d, loc := now.Year(), now.Month(), now.Day(), now.Location()
 	s := strings.ReplaceAll(input, ":", "")
 	var choices []time.Time
@@ -333,3 +474,125 @@ func timeSuggestions(input string) []time.Time {
 
 	return choices
 }
+
+// weekdayOptions are the day toggles of /add and /edit.
+var weekdayOptions = map[string]time.Weekday{
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+	"sunday":    time.Sunday,
+}
+
+// parseRecurrenceOptions builds the recurrence of a new trip, either from the
+// recurrence option or from the day toggles which default to every day.
+func parseRecurrenceOptions(opts OptionMap, now time.Time) (database.Recurrence, error) {
+	if value, ok := opts["recurrence"]; ok {
+		for day := range weekdayOptions {
+			if _, ok := opts[day]; ok {
+				return database.Recurrence{}, fmt.Errorf("use either recurrence or the day options, not both")
+			}
+		}
+		recurrence, err := database.ParseRecurrence(value.StringValue())
+		if err != nil {
+			return database.Recurrence{}, err
+		}
+		return recurrence.StartingOn(now.Format(database.DateLayout)), nil
+	}
+
+	days := make([]time.Weekday, 0, len(weekdayOptions))
+	for name, day := range weekdayOptions {
+		if val, ok := opts[name]; !ok || val.BoolValue() {
+			days = append(days, day)
+		}
+	}
+	return database.WeeklyOn(days...), nil
+}
+
+// recurrenceSuggestions are example rules offered while typing a recurrence.
+var recurrenceSuggestions = []string{
+	"weekdays",
+	"weekends",
+	"daily",
+	"FREQ=WEEKLY;INTERVAL=2;BYDAY=FR",
+	"FREQ=MONTHLY;BYDAY=1MO",
+	"FREQ=MONTHLY;BYDAY=-1FR",
+}
+
+// recurrenceChoices suggests example rules, with the input itself first if
+// it is a valid rule.
+func recurrenceChoices(input string) []*discordgo.ApplicationCommandOptionChoice {
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(recurrenceSuggestions)+1)
+	describe := func(rule string) string {
+		recurrence, _ := database.ParseRecurrence(rule)
+		return strings.ReplaceAll(recurrence.Describe(), "**", "")
+	}
+	input = strings.TrimSpace(input)
+	if _, err := database.ParseRecurrence(input); err == nil && !slices.Contains(recurrenceSuggestions, input) {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  fmt.Sprintf("%s (%s)", describe(input), input),
+			Value: input,
+		})
+	}
+	for _, rule := range recurrenceSuggestions {
+		name := fmt.Sprintf("%s (%s)", describe(rule), rule)
+		if input != "" && !strings.Contains(strings.ToLower(name), strings.ToLower(input)) {
+			continue
+		}
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  name,
+			Value: rule,
+		})
+	}
+	return choices
+}
+
+// parseTripDate validates the date option of a trip, "none" clears the date.
+func parseTripDate(input string, now time.Time) (string, error) {
+	input = strings.TrimSpace(input)
+	if input == "" || strings.EqualFold(input, "none") {
+		return "", nil
+	}
+	return parseDate(input, now)
+}
+
+// parseDate validates a date option, it has to be today or later.
+func parseDate(input string, now time.Time) (string, error) {
+	input = strings.TrimSpace(input)
+	date, err := time.ParseInLocation(database.DateLayout, input, now.Location())
+	if err != nil {
+		return "", fmt.Errorf("invalid date %q, use YYYY-MM-DD", input)
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if date.Before(today) {
+		return "", fmt.Errorf("%s has already passed", input)
+	}
+	return date.Format(database.DateLayout), nil
+}
+
+// dateChoices suggests the coming two weeks, narrowed down by input.
+func dateChoices(now time.Time, input string) []*discordgo.ApplicationCommandOptionChoice {
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, 14)
+	input = strings.ToLower(strings.TrimSpace(input))
+	for i := range 14 {
+		day := time.Date(now.Year(), now.Month(), now.Day()+i, 0, 0, 0, 0, now.Location())
+		value := day.Format(database.DateLayout)
+		name := day.Format("Monday 2 January") + " (" + value + ")"
+		switch i {
+		case 0:
+			name = "Today, " + name
+		case 1:
+			name = "Tomorrow, " + name
+		}
+		if input != "" && !strings.Contains(strings.ToLower(name), input) {
+			continue
+		}
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  name,
+			Value: value,
+		})
+	}
+	return choices
+}
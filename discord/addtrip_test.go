@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vincbro/pascal/blaise"
+	"github.com/vincbro/pascal/database"
+	"github.com/vincbro/pascal/state"
+	"github.com/vincbro/pascal/state/statetest"
+)
+
+const (
+	odenplan = "740021666"
+	tekniska = "740020749"
+)
+
+// newTestState builds a State on a statetest env, with a fake clock set to
+// now.
+func newTestState(t *testing.T, now time.Time) (*state.State, *state.FakeClock, *database.User) {
+	t.Helper()
+	env := statetest.New(t)
+	clock := state.NewFakeClock(now)
+	s := state.NewState(env.DB, env.Server.Client(), "")
+	s.Clock = clock
+	s.Rechecks = nil
+	return s, clock, env.User
+}
+
+func TestAddTripAlerts(t *testing.T) {
+	loc, err := time.LoadLocation(statetest.Zone)
+	if err != nil {
+		t.Fatalf("failed to load zone: %v", err)
+	}
+	s, clock, user := newTestState(t, time.Date(2026, 10, 19, 7, 0, 0, 0, loc))
+
+	itineraries, err := s.BClient.Routing(context.Background(), odenplan, tekniska, "08:00:00", true, routeOptions)
+	if err != nil {
+		t.Fatalf("failed to route: %v", err)
+	}
+	trip := &database.Trip{
+		ID:         "commute",
+		UserID:     user.ID,
+		Name:       "Work",
+		Time:       "08:00:00",
+		Departure:  true,
+		Recurrence: database.Recurrence{Freq: database.Daily, Interval: 1},
+		Alerts:     []blaise.Time{10 * 60},
+	}
+	embed, err := saveTrip(s, user, trip, itineraries[0])
+	if err != nil {
+		t.Fatalf("failed to save trip: %v", err)
+	}
+	if embed.Title != "✅ Saved: Work" {
+		t.Errorf("got embed %q", embed.Title)
+	}
+
+	saved, err := s.DB.GetTrip(user.ID, "commute")
+	if err != nil {
+		t.Fatalf("saved trip is missing: %v", err)
+	}
+	if saved.FromID != odenplan || saved.ToID != tekniska || saved.ExpectedItinerary.DepartureTime != 28800 {
+		t.Errorf("saved %s ➔ %s departing %d", saved.FromID, saved.ToID, saved.ExpectedItinerary.DepartureTime)
+	}
+	if user, _ := s.DB.GetUser(user.ID); len(user.Locations) != 2 {
+		t.Errorf("user history has %d places, want both ends of the trip", len(user.Locations))
+	}
+
+	received := make(chan state.Request, 8)
+	s.AddHandler(func(s *state.State, request state.Request) error {
+		received <- request
+		return nil
+	})
+	s.Start()
+	t.Cleanup(s.Stop)
+	// The scheduler's queue and midnight timers and the hourly data check
+	clock.BlockUntil(3)
+
+	clock.Advance(50 * time.Minute)
+	select {
+	case request := <-received:
+		if request.Kind != state.RequestDepartSoon || request.TripID != "commute" || request.UserID != user.ID {
+			t.Errorf("got request %+v, want the 07:50 depart soon alert", request)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the alert of the new trip was never sent")
+	}
+}
+
+func TestSaveDepartedTrip(t *testing.T) {
+	loc, err := time.LoadLocation(statetest.Zone)
+	if err != nil {
+		t.Fatalf("failed to load zone: %v", err)
+	}
+	s, _, user := newTestState(t, time.Date(2026, 10, 19, 8, 30, 0, 0, loc))
+	itineraries, err := s.BClient.Routing(context.Background(), odenplan, tekniska, "08:00:00", true, 1)
+	if err != nil {
+		t.Fatalf("failed to route: %v", err)
+	}
+
+	tests := []struct {
+		date string
+		err  error
+	}{
+		{"2026-10-19", errDeparted},
+		{"2026-10-20", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.date, func(t *testing.T) {
+			trip := &database.Trip{ID: tt.date, UserID: user.ID, Name: "Once", Time: "08:00:00", Departure: true, Date: tt.date}
+			_, err := saveTrip(s, user, trip, itineraries[0])
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+			_, err = s.DB.GetTrip(user.ID, tt.date)
+			if saved := err == nil; saved != (tt.err == nil) {
+				t.Errorf("trip saved %v, want %v", saved, tt.err == nil)
+			}
+		})
+	}
+}
+
+func TestEditDepartedTrip(t *testing.T) {
+	loc, err := time.LoadLocation(statetest.Zone)
+	if err != nil {
+		t.Fatalf("failed to load zone: %v", err)
+	}
+	s, _, user := newTestState(t, time.Date(2026, 10, 19, 8, 30, 0, 0, loc))
+	itineraries, err := s.BClient.Routing(context.Background(), odenplan, tekniska, "08:00:00", true, 1)
+	if err != nil {
+		t.Fatalf("failed to route: %v", err)
+	}
+	trip := &database.Trip{ID: "once", UserID: user.ID, Name: "Once", Time: "08:00:00", Departure: true, Date: "2026-10-20"}
+	if _, err := saveTrip(s, user, trip, itineraries[0]); err != nil {
+		t.Fatalf("failed to save trip: %v", err)
+	}
+
+	// Moving it to today, after it has left, must not lose the trip
+	trip.Date = "2026-10-19"
+	if err := updateTrip(s, user, trip); !errors.Is(err, errDeparted) {
+		t.Fatalf("got error %v, want errDeparted", err)
+	}
+	saved, err := s.DB.GetTrip(user.ID, "once")
+	if err != nil || saved.Date != "2026-10-20" {
+		t.Fatalf("trip is %+v, %v after a rejected edit, want it unchanged", saved, err)
+	}
+
+	trip.Date = "2026-10-21"
+	if err := updateTrip(s, user, trip); err != nil {
+		t.Fatalf("failed to move the trip: %v", err)
+	}
+	if saved, err := s.DB.GetTrip(user.ID, "once"); err != nil || saved.Date != "2026-10-21" {
+		t.Errorf("trip is %+v, %v, want it moved to 2026-10-21", saved, err)
+	}
+}
+
+func TestDraftsAreKeptUntilTaken(t *testing.T) {
+	now := time.Date(2026, 10, 19, 8, 0, 0, 0, time.UTC)
+	id := drafts.put(tripDraft{trip: database.Trip{Name: "Work"}}, now)
+
+	// A failed save leaves the draft for another try
+	if _, ok := drafts.get(id, now); !ok {
+		t.Fatal("draft is missing")
+	}
+	if draft, ok := drafts.take(id, now); !ok || draft.trip.Name != "Work" {
+		t.Fatal("draft was not kept after get")
+	}
+	if _, ok := drafts.get(id, now); ok {
+		t.Error("draft is still there after it was taken")
+	}
+
+	id = drafts.put(tripDraft{}, now)
+	if _, ok := drafts.get(id, now.Add(draftTTL+time.Second)); ok {
+		t.Error("got an expired draft")
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/vincbro/pascal/blaise"
+	"github.com/vincbro/pascal/database"
+	"github.com/vincbro/pascal/state"
+)
+
+// snoozeDuration is how long the Snooze button holds back the alerts.
+const snoozeDuration = 5 * time.Minute
+
+// alertComponents returns the buttons sent along with an alert. The custom_ids
+// carry the service date so a button on an old alert can't touch today's trip.
+func alertComponents(request state.Request) []discordgo.MessageComponent {
+	if request.Kind == state.RequestArrival || request.Kind == state.RequestDeparted {
+		return []discordgo.MessageComponent{}
+	}
+	buttons := []discordgo.MessageComponent{
+		discordgo.Button{
+			Label:    "Mute today",
+			Style:    discordgo.SecondaryButton,
+			Emoji:    &discordgo.ComponentEmoji{Name: "🤫"},
+			CustomID: ComponentID("alert_mute", request.TripID, request.ServiceDate),
+		},
+	}
+	// Snoozing or skipping only makes sense before leaving
+	if request.Kind == state.RequestDepartSoon || request.Kind == state.RequestTripChanged {
+		buttons = append(buttons,
+			discordgo.Button{
+				Label:    "Snooze 5 min",
+				Style:    discordgo.SecondaryButton,
+				Emoji:    &discordgo.ComponentEmoji{Name: "💤"},
+				CustomID: ComponentID("alert_snooze", request.TripID, request.ServiceDate),
+			},
+			discordgo.Button{
+				Label:    "Skip this trip",
+				Style:    discordgo.DangerButton,
+				Emoji:    &discordgo.ComponentEmoji{Name: "⏭️"},
+				CustomID: ComponentID("alert_skip", request.TripID, request.ServiceDate),
+			},
+		)
+	}
+	buttons = append(buttons, discordgo.Button{
+		Label:    "Show route",
+		Style:    discordgo.PrimaryButton,
+		Emoji:    &discordgo.ComponentEmoji{Name: "🧭"},
+		CustomID: ComponentID("alert_route", request.TripID),
+	})
+	return []discordgo.MessageComponent{discordgo.ActionsRow{Components: buttons}}
+}
+
+// alertTrip returns the trip behind an alert button, or answers the
+// interaction itself if the alert is no longer for the current occurrence.
+func alertTrip(s *discordgo.Session, i *discordgo.InteractionCreate, state *state.State, args []string) (*database.Trip, bool, error) {
+	user, err := GetUser(i.User, i.ChannelID, state)
+	if err != nil {
+		return nil, false, err
+	}
+	trip, err := state.DB.GetTrip(user.ID, args[0])
+	if err != nil {
+		return nil, false, err
+	}
+	if len(args) > 1 {
+		if meta, ok := state.Meta.Get(trip.ID); !ok || meta.ServiceDate != args[1] {
+			return nil, false, respondEphemeral(s, i, fmt.Sprintf("⌛ This alert is for an earlier **%s**, it has already left.", trip.Name), nil)
+		}
+	}
+	return trip, true, nil
+}
+
+// respondEphemeral answers the interaction with a message only the user can see.
+func respondEphemeral(s *discordgo.Session, i *discordgo.InteractionCreate, content string, components []discordgo.MessageComponent) error {
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content:    content,
+			Components: components,
+			Flags:      discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+func alertMuteHandler(s *discordgo.Session, i *discordgo.InteractionCreate, state *state.State, args []string) error {
+	trip, ok, err := alertTrip(s, i, state, args)
+	if !ok {
+		return err
+	}
+	if err := state.MuteTrip(trip.ID); err != nil {
+		return err
+	}
+	return respondEphemeral(s, i, fmt.Sprintf("🔕 **%s** muted for today.", trip.Name), []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "Unmute",
+					Style:    discordgo.SecondaryButton,
+					Emoji:    &discordgo.ComponentEmoji{Name: "🔔"},
+					CustomID: ComponentID("alert_unmute", args...),
+				},
+			},
+		},
+	})
+}
+
+func alertUnmuteHandler(s *discordgo.Session, i *discordgo.InteractionCreate, state *state.State, args []string) error {
+	trip, ok, err := alertTrip(s, i, state, args)
+	if !ok {
+		return err
+	}
+	if err := state.UnMuteTrip(trip.ID); err != nil {
+		return err
+	}
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    fmt.Sprintf("🔔 **%s** unmuted.", trip.Name),
+			Components: []discordgo.MessageComponent{},
+		},
+	})
+}
+
+func alertSnoozeHandler(s *discordgo.Session, i *discordgo.InteractionCreate, state *state.State, args []string) error {
+	trip, ok, err := alertTrip(s, i, state, args)
+	if !ok {
+		return err
+	}
+	state.SnoozeTrip(trip.ID, snoozeDuration)
+	return respondEphemeral(s, i, fmt.Sprintf("💤 I'll remind you about **%s** again in %d min.", trip.Name, int(snoozeDuration.Minutes())), nil)
+}
+
+func alertSkipHandler(s *discordgo.Session, i *discordgo.InteractionCreate, state *state.State, args []string) error {
+	trip, ok, err := alertTrip(s, i, state, args)
+	if !ok {
+		return err
+	}
+	if err := state.DB.AddSkipDate(&database.SkipDate{TripID: trip.ID, Date: args[1]}); err != nil {
+		return err
+	}
+	return respondEphemeral(s, i, fmt.Sprintf("⏭️ Skipping **%s** today, undo it with `%s`.", trip.Name, skipUndoCommand(trip, args[1])), nil)
+}
+
+func alertRouteHandler(s *discordgo.Session, i *discordgo.InteractionCreate, state *state.State, args []string) error {
+	trip, ok, err := alertTrip(s, i, state, args)
+	if !ok {
+		return err
+	}
+	itinerary := trip.ExpectedItinerary
+	embed := &discordgo.MessageEmbed{
+		Title: fmt.Sprintf("🧭 %s", trip.Name),
+		Description: fmt.Sprintf("Departing **%s** and arriving **%s**",
+			itinerary.DepartureTime.ToHMSString(),
+			itinerary.ArrivalTime.ToHMSString(),
+		),
+		Color:  0x57F287,
+		Fields: blaise.IteniraryToEmbedFields(itinerary),
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: "Pascal • Watching your commute",
+		},
+	}
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{embed},
+			Flags:  discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/vincbro/pascal/database"
+	"github.com/vincbro/pascal/state"
+)
+
+func CreateAlertsCommand() Command {
+	return Command{
+		Definition: &discordgo.ApplicationCommand{
+			Name:        "alerts",
+			Description: "Change when you get alerted, for a single trip or as your default",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:         "alerts",
+					Description:  "A preset or minutes before departure (e.g. 30, 10, 5)",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Required:     true,
+					Autocomplete: true,
+				},
+				{
+					Name:         "name",
+					Description:  "The trip to change, leave empty to change your default",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Autocomplete: true,
+				},
+			},
+		},
+		Handler:      alertsHandler,
+		Autocomplete: alertsAutocomplete,
+	}
+}
+
+func alertsHandler(s *discordgo.Session, i *discordgo.InteractionCreate, state *state.State) error {
+	user, err := GetUser(i.User, i.ChannelID, state)
+	if err != nil {
+		return err
+	}
+	opts := ParseOptions(i.ApplicationCommandData().Options)
+
+	alerts, err := database.ParseAlerts(opts["alerts"].StringValue())
+	if err != nil {
+		return err
+	}
+
+	var embed *discordgo.MessageEmbed
+	if value, ok := opts["name"]; ok {
+		trip, err := state.DB.GetTrip(user.ID, value.StringValue())
+		if err != nil {
+			return err
+		}
+		trip.Alerts = alerts
+		if err = state.DB.UpdateTrip(trip); err != nil {
+			return err
+		}
+		state.ScheduleTrip(trip)
+
+		embed = &discordgo.MessageEmbed{
+			Title:       fmt.Sprintf("🔔 Updated alerts: %s", trip.Name),
+			Description: fmt.Sprintf("I'll alert you **%s** before departure.", database.FormatAlerts(trip.AlertOffsets(user))),
+			Color:       0x57F287,
+			Footer: &discordgo.MessageEmbedFooter{
+				Text: "Pascal • Watching your commute",
+			},
+		}
+	} else {
+		user.Alerts = alerts
+		if err = state.DB.UpdateUser(user); err != nil {
+			return err
+		}
+		if err = state.RescheduleUser(user.ID); err != nil {
+			return err
+		}
+
+		embed = &discordgo.MessageEmbed{
+			Title:       "🔔 Updated default alerts",
+			Description: fmt.Sprintf("Unless a trip says otherwise I'll alert you **%s** before departure.", database.FormatAlerts(database.Trip{}.AlertOffsets(user))),
+			Color:       0x57F287,
+			Footer: &discordgo.MessageEmbedFooter{
+				Text: "Pascal • Watching your commute",
+			},
+		}
+	}
+
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{embed},
+		},
+	})
+
+	return err
+}
+
+func alertsAutocomplete(s *discordgo.Session, i *discordgo.InteractionCreate, state *state.State) error {
+	data := i.ApplicationCommandData()
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, 20)
+
+	for _, option := range data.Options {
+		if !option.Focused {
+			continue
+		}
+		switch option.Name {
+		case "name":
+			return listAutocomplete(s, i, state)
+		case "alerts":
+			choices = append(choices, alertChoices(option.StringValue())...)
+		}
+	}
+
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{
+			Choices: choices,
+		},
+	})
+}
+
+// alertChoices suggests the alert presets matching input, with the input
+// itself first if it is a valid list of minutes.
+func alertChoices(input string) []*discordgo.ApplicationCommandOptionChoice {
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(database.AlertPresets)+2)
+	input = strings.TrimSpace(input)
+	if alerts, err := database.ParseAlerts(input); err == nil && alerts != nil {
+		if _, ok := database.AlertPresets[strings.ToLower(input)]; !ok {
+			choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+				Name:  database.FormatAlerts(alerts),
+				Value: input,
+			})
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(database.AlertPresets)) {
+		if !strings.HasPrefix(name, strings.ToLower(input)) {
+			continue
+		}
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  fmt.Sprintf("%s (%s)", name, database.FormatAlerts(database.AlertPresets[name])),
+			Value: name,
+		})
+	}
+	if strings.HasPrefix("default", strings.ToLower(input)) {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  "default (use the next level default)",
+			Value: "default",
+		})
+	}
+	return choices
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/vincbro/pascal/database"
+	"github.com/vincbro/pascal/state"
+)
+
+func CreateAwayCommand() Command {
+	return Command{
+		Definition: &discordgo.ApplicationCommand{
+			Name:        "away",
+			Description: "Pause all your trips while you are away",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "add",
+					Description: "Add days where none of your trips run",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:         "from",
+							Description:  "The first day you are away (YYYY-MM-DD)",
+							Type:         discordgo.ApplicationCommandOptionString,
+							Required:     true,
+							Autocomplete: true,
+						},
+						{
+							Name:         "to",
+							Description:  "The last day you are away (YYYY-MM-DD), defaults to the first day",
+							Type:         discordgo.ApplicationCommandOptionString,
+							Autocomplete: true,
+						},
+						{
+							Name:        "reason",
+							Description: "Why you are away, e.g. Vacation",
+							Type:        discordgo.ApplicationCommandOptionString,
+						},
+					},
+				},
+				{
+					Name:        "remove",
+					Description: "Remove days you were going to be away",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:         "days",
+							Description:  "The days to remove",
+							Type:         discordgo.ApplicationCommandOptionString,
+							Required:     true,
+							Autocomplete: true,
+						},
+					},
+				},
+				{
+					Name:        "list",
+					Description: "List the days you are away",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+				},
+			},
+		},
+		Handler:      awayHandler,
+		Autocomplete: awayAutocomplete,
+	}
+}
+
+func awayHandler(s *discordgo.Session, i *discordgo.InteractionCreate, state *state.State) error {
+	user, err := GetUser(i.User, i.ChannelID, state)
+	if err != nil {
+		return err
+	}
+	sub := i.ApplicationCommandData().Options[0]
+	opts := ParseOptions(sub.Options)
+
+	var embed *discordgo.MessageEmbed
+	switch sub.Name {
+	case "add":
+		now := userNow(state, user)
+		from, err := parseDate(opts["from"].StringValue(), now)
+		if err != nil {
+			return err
+		}
+		to := from
+		if val, ok := opts["to"]; ok {
+			to, err = parseDate(val.StringValue(), now)
+			if err != nil {
+				return err
+			}
+		}
+		if to < from {
+			return fmt.Errorf("%s is before %s", to, from)
+		}
+		away := &database.Away{UserID: user.ID, StartDate: from, EndDate: to}
+		if val, ok := opts["reason"]; ok {
+			away.Reason = val.StringValue()
+		}
+		if err = state.DB.AddAway(away); err != nil {
+			return err
+		}
+
+		embed = &discordgo.MessageEmbed{
+			Title:       "🏖️ Away",
+			Description: fmt.Sprintf("I won't alert you for any trips %s.", formatAway(away)),
+			Color:       0x57F287,
+		}
+	case "remove":
+		id, err := strconv.ParseUint(opts["days"].StringValue(), 10, 0)
+		if err != nil {
+			return fmt.Errorf("invalid away days %q, pick one from the list", opts["days"].StringValue())
+		}
+		if err = state.DB.RemoveAway(user.ID, uint(id)); err != nil {
+			return err
+		}
+
+		embed = &discordgo.MessageEmbed{
+			Title:       "🗑️ Removed",
+			Description: "Your trips run as usual on those days again.",
+			Color:       0x57F287,
+		}
+	case "list":
+		aways, err := state.DB.GetAways(user.ID)
+		if err != nil {
+			return err
+		}
+		lines := make([]string, 0, len(aways))
+		for _, away := range aways {
+			lines = append(lines, fmt.Sprintf("• %s", formatAway(away)))
+		}
+		desc := "You haven't said you are away, you can with /away add"
+		if len(lines) > 0 {
+			desc = strings.Join(lines, "\n")
+		}
+
+		embed = &discordgo.MessageEmbed{
+			Title:       "🏖️ Away",
+			Description: desc,
+			Color:       0x57F287,
+		}
+	}
+	embed.Footer = &discordgo.MessageEmbedFooter{
+		Text: "Pascal • Watching your commute",
+	}
+
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{embed},
+		},
+	})
+
+	return err
+}
+
+func formatAway(away *database.Away) string {
+	text := fmt.Sprintf("on **%s**", away.StartDate)
+	if away.EndDate != away.StartDate {
+		text = fmt.Sprintf("from **%s** to **%s**", away.StartDate, away.EndDate)
+	}
+	if away.Reason != "" {
+		text += fmt.Sprintf(" (%s)", away.Reason)
+	}
+	return text
+}
+
+func awayAutocomplete(s *discordgo.Session, i *discordgo.InteractionCreate, state *state.State) error {
+	user, err := GetUser(i.User, i.ChannelID, state)
+	if err != nil {
+		return err
+	}
+	sub := i.ApplicationCommandData().Options[0]
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, 20)
+
+	for _, option := range sub.Options {
+		if !option.Focused {
+			continue
+		}
+		switch option.Name {
+		case "from", "to":
+			choices = append(choices, dateChoices(userNow(state, user), option.StringValue())...)
+		case "days":
+			aways, err := state.DB.GetAways(user.ID)
+			if err != nil {
+				return err
+			}
+			for _, away := range aways {
+				choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+					Name:  strings.ReplaceAll(formatAway(away), "**", ""),
+					Value: strconv.FormatUint(uint64(away.ID), 10),
+				})
+			}
+		}
+	}
+
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{
+			Choices: choices,
+		},
+	})
+}
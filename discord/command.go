@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/vincbro/pascal/database"
@@ -54,11 +55,29 @@ func (c *Commands) Add(command Command) {
 func GetCommands() Commands {
 	c := make(Commands)
 	c.Add(CreateAddTripCommand())
+	c.Add(CreateNewTripCommand())
+	c.Add(CreateEditTripCommand())
 	c.Add(CreateRemoveTripCommand())
 	c.Add(CreateListCommand())
+	c.Add(CreateAlertsCommand())
+	c.Add(CreateRouteCommand())
+	c.Add(CreateAwayCommand())
+	c.Add(CreateSkipCommand())
+	c.Add(CreatePlaceCommand())
+	c.Add(CreatePauseCommand())
+	c.Add(CreateResumeCommand())
+	c.Add(CreateVacationCommand())
+	c.Add(CreateSettingsCommand())
+	c.Add(CreateDeparturesCommand())
 	return c
 }
 
+// userNow is the current time in the time zone of the user, which is what
+// "today" and bare times typed by the user are relative to.
+func userNow(state *state.State, user *database.User) time.Time {
+	return state.Clock.Now().In(user.Location())
+}
+
 type OptionMap map[string]*discordgo.ApplicationCommandInteractionDataOption
 
 func ParseOptions(options []*discordgo.ApplicationCommandInteractionDataOption) OptionMap {
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/google/uuid"
+
+	"github.com/vincbro/pascal/blaise"
+	"github.com/vincbro/pascal/database"
+	"github.com/vincbro/pascal/state"
+)
+
+// ComponentHandler handles a message component interaction, args are the
+// parts of the custom_id after the handler name.
+type ComponentHandler func(s *discordgo.Session, i *discordgo.InteractionCreate, state *state.State, args []string) error
+
+// Component is a handler and the least number of args its custom_ids carry,
+// handlers can index that many args without checking.
+type Component struct {
+	Handler ComponentHandler
+	Args    int
+}
+
+type Components map[string]*Component
+
+func GetComponents() Components {
+	c := make(Components)
+	c["add_pick"] = &Component{Handler: addTripPickHandler, Args: 1}
+	c["route_save"] = &Component{Handler: routeSaveHandler, Args: 1}
+	c["route_remind"] = &Component{Handler: routeRemindHandler, Args: 1}
+	c["new_confirm"] = &Component{Handler: newTripConfirmHandler, Args: 1}
+	c["new_cancel"] = &Component{Handler: newTripCancelHandler, Args: 1}
+	// Alerts sent before buttons carried the service date only have the trip
+	c["alert_mute"] = &Component{Handler: alertMuteHandler, Args: 1}
+	c["alert_unmute"] = &Component{Handler: alertUnmuteHandler, Args: 1}
+	c["alert_snooze"] = &Component{Handler: alertSnoozeHandler, Args: 1}
+	c["alert_skip"] = &Component{Handler: alertSkipHandler, Args: 2}
+	c["alert_route"] = &Component{Handler: alertRouteHandler, Args: 1}
+	return c
+}
+
+// ErrBadComponent is returned for a custom_id that doesn't match any
+// component, e.g. a button of an older version of pascal.
+var ErrBadComponent = errors.New("unknown or malformed component")
+
+// lookup finds the component a custom_id is routed to and its args.
+func (c Components) lookup(customID string) (*Component, []string, error) {
+	parts := strings.Split(customID, ":")
+	comp, ok := c[parts[0]]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %q", ErrBadComponent, customID)
+	}
+	args := parts[1:]
+	if len(args) < comp.Args {
+		return nil, nil, fmt.Errorf("%w: %q has %d args, want %d", ErrBadComponent, customID, len(args), comp.Args)
+	}
+	return comp, args, nil
+}
+
+// ComponentID builds a custom_id that is routed to the handler registered
+// under name, e.g. "add_pick:<draft id>".
+func ComponentID(name string, args ...string) string {
+	return strings.Join(append([]string{name}, args...), ":")
+}
+
+// draftTTL is how long a trip waiting for the user to pick a route is kept.
+const draftTTL = 15 * time.Minute
+
+// tripDraft is a trip that is not saved until the user has picked one of the
+// itineraries in options.
+type tripDraft struct {
+	trip    database.Trip
+	options []blaise.Itinerary
+	expires time.Time
+}
+
+type draftStore struct {
+	mu     sync.Mutex
+	drafts map[string]tripDraft
+}
+
+var drafts = &draftStore{drafts: make(map[string]tripDraft)}
+
+func (d *draftStore) put(draft tripDraft, now time.Time) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for id, old := range d.drafts {
+		if now.After(old.expires) {
+			delete(d.drafts, id)
+		}
+	}
+	id := uuid.New().String()
+	draft.expires = now.Add(draftTTL)
+	d.drafts[id] = draft
+	return id
+}
+
+// get returns the draft without removing it, if it hasn't expired. Drafts
+// are only taken once they have been saved, so the user can try again.
+func (d *draftStore) get(id string, now time.Time) (tripDraft, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	draft, ok := d.drafts[id]
+	if !ok || now.After(draft.expires) {
+		return tripDraft{}, false
+	}
+	return draft, true
+}
+
+// take removes and returns the draft, if it hasn't expired.
+func (d *draftStore) take(id string, now time.Time) (tripDraft, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	draft, ok := d.drafts[id]
+	delete(d.drafts, id)
+	if !ok || now.After(draft.expires) {
+		return tripDraft{}, false
+	}
+	return draft, true
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestComponentLookup(t *testing.T) {
+	comps := GetComponents()
+	tests := []struct {
+		id   string
+		args []string
+		err  bool
+	}{
+		{id: ComponentID("add_pick", "draft", "1"), args: []string{"draft", "1"}},
+		{id: ComponentID("alert_skip", "trip", "2026-10-19"), args: []string{"trip", "2026-10-19"}},
+		{id: ComponentID("alert_mute", "trip"), args: []string{"trip"}},
+		{id: ComponentID("alert_skip", "trip"), err: true},
+		{id: "add_pick", err: true},
+		{id: "route_remind", err: true},
+		{id: "no_such_button:trip", err: true},
+		{id: "", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			comp, args, err := comps.lookup(tt.id)
+			if tt.err {
+				if !errors.Is(err, ErrBadComponent) {
+					t.Errorf("got error %v, want ErrBadComponent", err)
+				}
+				return
+			}
+			if err != nil || comp == nil {
+				t.Fatalf("failed to look up: %v", err)
+			}
+			if !slices.Equal(args, tt.args) {
+				t.Errorf("args %v, want %v", args, tt.args)
+			}
+		})
+	}
+
+	// Every registered component can be looked up with the args it asks for
+	for name, comp := range comps {
+		if _, _, err := comps.lookup(ComponentID(name, make([]string, comp.Args)...)); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
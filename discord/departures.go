@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/vincbro/pascal/blaise"
+	"github.com/vincbro/pascal/database"
+	"github.com/vincbro/pascal/state"
+)
+
+const (
+	// defaultDepartures is how many departures are shown unless asked otherwise
+	defaultDepartures = 10
+	// departuresRefreshEvery and departuresRefreshFor control how a board with
+	// refresh on is kept up to date
+	departuresRefreshEvery = 30 * time.Second
+	departuresRefreshFor   = 5 * time.Minute
+)
+
+func CreateDeparturesCommand() Command {
+	minCount := float64(1)
+	return Command{
+		Definition: &discordgo.ApplicationCommand{
+			Name:        "departures",
+			Description: "Show the next departures from a stop",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:         "stop",
+					Description:  "The stop to leave from",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Required:     true,
+					Autocomplete: true,
+				},
+				{
+					Name:        "count",
+					Description: fmt.Sprintf("How many departures to show, defaults to %d", defaultDepartures),
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					MinValue:    &minCount,
+					MaxValue:    50,
+				},
+				{
+					Name:        "refresh",
+					Description: fmt.Sprintf("Keep the board up to date for %d min", int(departuresRefreshFor.Minutes())),
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+				},
+			},
+		},
+		Handler:      departuresHandler,
+		Autocomplete: departuresAutocomplete,
+	}
+}
+
+func departuresHandler(s *discordgo.Session, i *discordgo.InteractionCreate, state *state.State) error {
+	user, err := GetUser(i.User, i.ChannelID, state)
+	if err != nil {
+		return err
+	}
+	opts := ParseOptions(i.ApplicationCommandData().Options)
+
+	stop, err := lookupLocation(state, user, opts["stop"].StringValue())
+	if err != nil {
+		return err
+	}
+	user.AddHistory(stop)
+	if err = state.DB.UpdateUser(user); err != nil {
+		return err
+	}
+	count := defaultDepartures
+	if val, ok := opts["count"]; ok {
+		count = int(val.IntValue())
+	}
+	refresh := false
+	if val, ok := opts["refresh"]; ok {
+		refresh = val.BoolValue()
+	}
+
+	embed, err := departureBoard(state, user, stop, count, refresh)
+	if err != nil {
+		return err
+	}
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{embed},
+		},
+	})
+	if err != nil || !refresh {
+		return err
+	}
+
+	// The interaction token outlives the refresh by far, so the response can
+	// be edited without a message of our own
+	state.Go(func(done <-chan struct{}) {
+		refreshBoard(state, done, stop.ID,
+			func(refreshing bool) (*discordgo.MessageEmbed, error) {
+				return departureBoard(state, user, stop, count, refreshing)
+			},
+			func(embed *discordgo.MessageEmbed) error {
+				_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+					Embeds: &[]*discordgo.MessageEmbed{embed},
+				})
+				return err
+			})
+	})
+	return nil
+}
+
+// refreshBoard renders the board of the stop again every
+// departuresRefreshEvery and hands it to edit, for departuresRefreshFor. The
+// last board is rendered without the refreshing note. It gives up early if an
+// edit fails or done is closed.
+func refreshBoard(state *state.State, done <-chan struct{}, stopID string,
+	render func(refreshing bool) (*discordgo.MessageEmbed, error), edit func(*discordgo.MessageEmbed) error) {
+	until := state.Clock.Now().Add(departuresRefreshFor)
+	for {
+		select {
+		case <-state.Clock.After(departuresRefreshEvery):
+		case <-done:
+			return
+		}
+		last := !state.Clock.Now().Before(until)
+		embed, err := render(!last)
+		if err != nil {
+			slog.Warn("failed to refresh departures", "stop", stopID, "error", err)
+			if !last {
+				continue
+			}
+			return
+		}
+		if err := edit(embed); err != nil {
+			slog.Warn("failed to edit departures", "stop", stopID, "error", err)
+			return
+		}
+		if last {
+			return
+		}
+	}
+}
+
+// departureBoard fetches the next count departures from the stop and renders
+// them as of now for the user.
+func departureBoard(state *state.State, user *database.User, stop blaise.Location, count int, refreshing bool) (*discordgo.MessageEmbed, error) {
+	now := userNow(state, user)
+	departures, err := state.BClient.Departures(context.Background(), stop.ID, now.Format("15:04:05"), count)
+	if err != nil {
+		return nil, err
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:  fmt.Sprintf("🚏 Departures from %s", stop.Name),
+		Color:  0x57F287,
+		Fields: blaise.DeparturesToEmbedFields(departures, blaise.Time(now.Hour()*3600+now.Minute()*60+now.Second())),
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf("Pascal • Updated %s", now.Format("15:04:05")),
+		},
+	}
+	if len(departures) == 0 {
+		embed.Description = "No departures coming up."
+	}
+	if refreshing {
+		embed.Footer.Text += " • Refreshing"
+	}
+	return embed, nil
+}
+
+func departuresAutocomplete(s *discordgo.Session, i *discordgo.InteractionCreate, state *state.State) error {
+	user, err := GetUser(i.User, i.ChannelID, state)
+	if err != nil {
+		return err
+	}
+	data := i.ApplicationCommandData()
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, 20)
+
+	for _, option := range data.Options {
+		if !option.Focused || option.Name != "stop" {
+			continue
+		}
+		choices, err = locationChoices(state, user, option.StringValue())
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{
+			Choices: choices,
+		},
+	})
+}
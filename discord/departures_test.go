@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/vincbro/pascal/blaise"
+	"github.com/vincbro/pascal/state"
+	"github.com/vincbro/pascal/state/statetest"
+)
+
+type refreshRun struct {
+	edits, refreshing atomic.Int32
+	finished          chan struct{}
+}
+
+// startRefresh runs refreshBoard under s, every edit fails with editErr.
+func startRefresh(s *state.State, editErr error) *refreshRun {
+	run := &refreshRun{finished: make(chan struct{})}
+	s.Go(func(done <-chan struct{}) {
+		defer close(run.finished)
+		refreshBoard(s, done, odenplan,
+			func(refreshing bool) (*discordgo.MessageEmbed, error) {
+				if refreshing {
+					run.refreshing.Add(1)
+				}
+				return &discordgo.MessageEmbed{}, nil
+			},
+			func(*discordgo.MessageEmbed) error {
+				run.edits.Add(1)
+				return editErr
+			})
+	})
+	return run
+}
+
+func TestRefreshBoard(t *testing.T) {
+	now := time.Date(2026, 10, 19, 7, 0, 0, 0, time.UTC)
+
+	t.Run("runs out", func(t *testing.T) {
+		s, clock, _ := newTestState(t, now)
+		t.Cleanup(s.Stop)
+		run := startRefresh(s, nil)
+		rounds := int(departuresRefreshFor / departuresRefreshEvery)
+		for range rounds {
+			clock.BlockUntil(1)
+			clock.Advance(departuresRefreshEvery)
+		}
+		<-run.finished
+		// Only the last board drops the refreshing note
+		if run.edits.Load() != int32(rounds) || run.refreshing.Load() != int32(rounds-1) {
+			t.Errorf("got %d edits, %d refreshing, want %d and %d", run.edits.Load(), run.refreshing.Load(), rounds, rounds-1)
+		}
+	})
+
+	t.Run("failed edit", func(t *testing.T) {
+		s, clock, _ := newTestState(t, now)
+		t.Cleanup(s.Stop)
+		run := startRefresh(s, errors.New("unknown webhook"))
+		clock.BlockUntil(1)
+		clock.Advance(departuresRefreshEvery)
+		<-run.finished
+		if run.edits.Load() != 1 {
+			t.Errorf("got %d edits, want to give up after the first", run.edits.Load())
+		}
+	})
+
+	t.Run("state stops", func(t *testing.T) {
+		s, clock, _ := newTestState(t, now)
+		run := startRefresh(s, nil)
+		clock.BlockUntil(1)
+		clock.Advance(departuresRefreshEvery)
+		clock.BlockUntil(1)
+
+		// Stop must not wait for the refresh to run out
+		stopped := make(chan struct{})
+		go func() {
+			s.Stop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Stop waited for the refresh to run out")
+		}
+		select {
+		case <-run.finished:
+		default:
+			t.Error("the refresh is still running after Stop")
+		}
+		if run.edits.Load() != 1 {
+			t.Errorf("got %d edits, want 1 before stopping", run.edits.Load())
+		}
+
+		ran := false
+		s.Go(func(<-chan struct{}) { ran = true })
+		if ran {
+			t.Error("ran after the state stopped")
+		}
+	})
+}
+
+func TestDepartureBoard(t *testing.T) {
+	loc, err := time.LoadLocation(statetest.Zone)
+	if err != nil {
+		t.Fatalf("failed to load zone: %v", err)
+	}
+	s, _, user := newTestState(t, time.Date(2026, 10, 19, 7, 58, 30, 0, loc))
+	stop := blaise.Location{ID: odenplan, Name: "Odenplan"}
+
+	embed, err := departureBoard(s, user, stop, 4, true)
+	if err != nil {
+		t.Fatalf("failed to render board: %v", err)
+	}
+	if embed.Title != "🚏 Departures from Odenplan" || embed.Footer.Text != "Pascal • Updated 07:58:30 • Refreshing" {
+		t.Errorf("got board %q with footer %q", embed.Title, embed.Footer.Text)
+	}
+	// The 4 leaving 07:59 is first and its two departures share a line
+	want := []struct{ name, value string }{
+		{"🚌 4 (Towards Radiohuset)", "`07:59` (now) • `08:04` (5 min)"},
+		{"🚇 19 (Towards Hagsätra)", "`08:00` (1 min)"},
+		{"🚇 17 (Towards Åkeshov)", "`08:05` (6 min)"},
+	}
+	if len(embed.Fields) != len(want) {
+		t.Fatalf("got %d lines, want %d", len(embed.Fields), len(want))
+	}
+	for i, field := range embed.Fields {
+		if field.Name != want[i].name || field.Value != want[i].value {
+			t.Errorf("line %d is %q: %q, want %q: %q", i, field.Name, field.Value, want[i].name, want[i].value)
+		}
+	}
+
+	empty, err := departureBoard(s, user, blaise.Location{ID: "740021705", Name: "Solna centrum"}, 4, false)
+	if err != nil || empty.Description != "No departures coming up." || empty.Footer.Text != "Pascal • Updated 07:58:30" {
+		t.Errorf("got board %+v, %v for a stop without departures", empty, err)
+	}
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/vincbro/pascal/database"
+	"github.com/vincbro/pascal/state"
+)
+
+func CreateEditTripCommand() Command {
+	return Command{
+		Definition: &discordgo.ApplicationCommand{
+			Name:        "edit",
+			Description: "Change an existing trip",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:         "name",
+					Description:  "The trip you want to change",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Required:     true,
+					Autocomplete: true,
+				},
+				{
+					Name:        "rename",
+					Description: "The new name of your trip",
+					Type:        discordgo.ApplicationCommandOptionString,
+				},
+				{
+					Name:         "from",
+					Description:  "The new departure point of you trip",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Autocomplete: true,
+				},
+				{
+					Name:         "to",
+					Description:  "The new destination point of you trip",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Autocomplete: true,
+				},
+				{
+					Name:        "type",
+					Description: "Is this the time you want to leave or the time you want to arrive?",
+					Type:        discordgo.ApplicationCommandOptionString,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "Arrive By", Value: "arrive"},
+						{Name: "Depart At", Value: "depart"},
+					},
+				},
+				{
+					Name:         "time",
+					Description:  "The new time you want to departe or arrive at",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Autocomplete: true,
+				},
+				{
+					Name:        "monday",
+					Description: "Should the trip run on Monday",
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+				},
+				{
+					Name:        "tuesday",
+					Description: "Should the trip run on Tuesday",
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+				},
+				{
+					Name:        "wednesday",
+					Description: "Should the trip run on Wednesday",
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+				},
+				{
+					Name:        "thursday",
+					Description: "Should the trip run on Thursday",
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+				},
+				{
+					Name:        "friday",
+					Description: "Should the trip run on Friday",
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+				},
+				{
+					Name:        "saturday",
+					Description: "Should the trip run on Saturday",
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+				},
+				{
+					Name:        "sunday",
+					Description: "Should the trip run on Sunday",
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+				},
+				{
+					Name:         "alerts",
+					Description:  "When to alert you, a preset or minutes before departure (e.g. 30, 10, 5)",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Autocomplete: true,
+				},
+				{
+					Name:         "recurrence",
+					Description:  "Repeat by a rule instead of the days, e.g. FREQ=WEEKLY;INTERVAL=2;BYDAY=FR",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Autocomplete: true,
+				},
+				{
+					Name:         "date",
+					Description:  "Run the trip once on this date (YYYY-MM-DD), or none to repeat it again",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Autocomplete: true,
+				},
+			},
+		},
+		Handler:      editTripHandler,
+		Autocomplete: editTripAutocomplete,
+	}
+}
+
+func editTripHandler(s *discordgo.Session, i *discordgo.InteractionCreate, state *state.State) error {
+	user, err := GetUser(i.User, i.ChannelID, state)
+	if err != nil {
+		return err
+	}
+	opts := ParseOptions(i.ApplicationCommandData().Options)
+
+	trip, err := state.DB.GetTrip(user.ID, opts["name"].StringValue())
+	if err != nil {
+		return err
+	}
+
+	reroute := false
+	fromID, toID := trip.FromID, trip.ToID
+	if val, ok := opts["rename"]; ok {
+		trip.Name = val.StringValue()
+	}
+	if val, ok := opts["from"]; ok {
+		fromID = resolvePlace(state, user, val.StringValue())
+		reroute = reroute || fromID != trip.FromID
+	}
+	if val, ok := opts["to"]; ok {
+		toID = resolvePlace(state, user, val.StringValue())
+		reroute = reroute || toID != trip.ToID
+	}
+	if val, ok := opts["type"]; ok {
+		departure := val.StringValue() == "depart"
+		reroute = reroute || departure != trip.Departure
+		trip.Departure = departure
+	}
+	if val, ok := opts["time"]; ok {
+		reroute = reroute || val.StringValue() != trip.Time
+		trip.Time = val.StringValue()
+	}
+
+	if _, ok := opts["recurrence"]; ok {
+		trip.Recurrence, err = parseRecurrenceOptions(opts, userNow(state, user))
+		if err != nil {
+			return err
+		}
+	} else if trip.Recurrence, err = toggleWeekdays(trip.Recurrence, opts); err != nil {
+		return err
+	}
+
+	if val, ok := opts["alerts"]; ok {
+		trip.Alerts, err = database.ParseAlerts(val.StringValue())
+		if err != nil {
+			return err
+		}
+	}
+
+	if val, ok := opts["date"]; ok {
+		trip.Date, err = parseTripDate(val.StringValue(), userNow(state, user))
+		if err != nil {
+			return err
+		}
+	}
+
+	if reroute {
+		iteniraries, err := state.BClient.Routing(context.Background(), fromID, toID, trip.Time, trip.Departure, 1)
+		if err != nil {
+			return err
+		}
+		itenirary := iteniraries[0]
+		trip.From = itenirary.From.Name
+		trip.FromID = itenirary.From.ID
+		trip.To = itenirary.To.Name
+		trip.ToID = itenirary.To.ID
+		trip.ExpectedItinerary = itenirary
+
+		user.AddHistory(itenirary.From)
+		user.AddHistory(itenirary.To)
+		if err = state.DB.UpdateUser(user); err != nil {
+			return err
+		}
+	}
+
+	if err = updateTrip(state, user, trip); err != nil {
+		return err
+	}
+
+	scheduleType := "Depart at"
+	if !trip.Departure {
+		scheduleType = "Arrive by"
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("✏️ Updated: %s", trip.Name),
+		Description: "I've updated this trip, its alerts start over from the next departure.",
+		Color:       0x57F287,
+		Fields: []*discordgo.MessageEmbedField{
+			{
+				Name:   "Route",
+				Value:  fmt.Sprintf("From: **%s**\nTo: **%s**", trip.From, trip.To),
+				Inline: true,
+			},
+			{
+				Name:   "Schedule",
+				Value:  fmt.Sprintf("%s **%s**\n%s", scheduleType, trip.Time, trip.FormatSchedule()),
+				Inline: true,
+			},
+			{
+				Name: "Possible trips",
+				Value: fmt.Sprintf("Found one departing **%s** and arriving **%s**\n(Travel time: %d min)",
+					trip.ExpectedItinerary.DepartureTime.ToHMSString(),
+					trip.ExpectedItinerary.ArrivalTime.ToHMSString(),
+					(trip.ExpectedItinerary.ArrivalTime-trip.ExpectedItinerary.DepartureTime)/60,
+				),
+				Inline: false,
+			},
+			{
+				Name:   "Alerts",
+				Value:  database.FormatAlerts(trip.AlertOffsets(user)),
+				Inline: false,
+			},
+		},
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: "Pascal • Watching your commute",
+		},
+	}
+
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{embed},
+		},
+	})
+
+	return err
+}
+
+// updateTrip stores the edited trip and schedules it again from its next
+// departure.
+func updateTrip(state *state.State, user *database.User, trip *database.Trip) error {
+	// A dated trip that has left would be removed right after saving it
+	if state.HasDeparted(trip, user) {
+		return errDeparted
+	}
+	if err := state.DB.UpdateTrip(trip); err != nil {
+		return err
+	}
+	state.ResetTrip(trip)
+	return nil
+}
+
+// editTripAutocomplete picks the trip with listAutocomplete and completes the
+// overrides like /add does.
+func editTripAutocomplete(s *discordgo.Session, i *discordgo.InteractionCreate, state *state.State) error {
+	for _, option := range i.ApplicationCommandData().Options {
+		if option.Focused && option.Name == "name" {
+			return listAutocomplete(s, i, state)
+		}
+	}
+	return addTripAutocomplete(s, i, state)
+}
+
+// toggleWeekdays applies the day toggles of /edit to a recurrence that only
+// picks days of the week.
+func toggleWeekdays(recurrence database.Recurrence, opts OptionMap) (database.Recurrence, error) {
+	toggled := false
+	for name := range weekdayOptions {
+		if _, ok := opts[name]; ok {
+			toggled = true
+		}
+	}
+	if !toggled {
+		return recurrence, nil
+	}
+	current, ok := recurrence.Weekdays()
+	if !ok {
+		return recurrence, fmt.Errorf("this trip repeats %s, change it with the recurrence option instead", strings.ToLower(strings.ReplaceAll(recurrence.Describe(), "**", "")))
+	}
+
+	days := make([]time.Weekday, 0, len(weekdayOptions))
+	for name, day := range weekdayOptions {
+		val, ok := opts[name]
+		if (ok && val.BoolValue()) || (!ok && slices.Contains(current, day)) {
+			days = append(days, day)
+		}
+	}
+	updated := database.WeeklyOn(days...)
+	updated.Until = recurrence.Until
+	return updated, nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/vincbro/pascal/blaise"
+	"github.com/vincbro/pascal/nlp"
+	"github.com/vincbro/pascal/state"
+)
+
+// errDeparted is returned when saving a trip that runs once and has already
+// left.
+var errDeparted = errors.New("the trip has already left")
+
+// errorMessage turns the error of a failed command into something that can be
+// shown to the user.
+func errorMessage(err error) string {
+	var parseErr *nlp.ParseError
+	switch {
+	case errors.As(err, &parseErr):
+		return fmt.Sprintf("I couldn't find a %s in that, %s.", parseErr.Missing, parseErr.Hint)
+	case errors.Is(err, errDeparted):
+		return "That trip has already left, pick a later time or date."
+	case errors.Is(err, ErrBadComponent):
+		return "That button is out of date, run the command again."
+	case errors.Is(err, state.ErrNotScheduled):
+		return "That trip has no upcoming alerts, there is nothing to mute."
+	case errors.Is(err, blaise.ErrNoRoute):
+		return "I couldn't find a route between those places at that time, try another time or nearby stop."
+	case errors.Is(err, blaise.ErrUnknownLocation):
+		return "I don't know one of those places, pick a suggestion from the list when typing."
+	case errors.Is(err, blaise.ErrDataLoading):
+		return "The timetables are being updated right now, try again in a few minutes."
+	case errors.Is(err, blaise.ErrUnavailable):
+		return "I can't reach the route planner right now, try again in a little while."
+	case errors.Is(err, blaise.ErrBadRequest):
+		return "The route planner didn't understand that request, check the time format (e.g. 08:30:00)."
+	default:
+		return fmt.Sprintf("Something went wrong: %s", err)
+	}
+}
+
+// respondError answers the interaction with a message only the user can see.
+// It fails if the interaction has already been responded to.
+func respondError(s *discordgo.Session, i *discordgo.InteractionCreate, err error) error {
+	embed := &discordgo.MessageEmbed{
+		Title:       "❌ That didn't work",
+		Description: errorMessage(err),
+		Color:       0xED4245,
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: "Pascal • Watching your commute",
+		},
+	}
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{embed},
+			Flags:  discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
@@ -9,7 +9,12 @@ import (
 	"github.com/vincbro/pascal/state"
 )
 
-func InteractionCreate(s *discordgo.Session, i *discordgo.InteractionCreate, cmds Commands, state *state.State) {
+func InteractionCreate(s *discordgo.Session, i *discordgo.InteractionCreate, cmds Commands, comps Components, state *state.State) {
+	if i.Type == discordgo.InteractionMessageComponent {
+		ComponentInteraction(s, i, comps, state)
+		return
+	}
+
 	data := i.ApplicationCommandData()
 	slog.Debug("Got interaction", "name", data.Name, "user", data.TargetID)
 	if cmd, ok := cmds[data.Name]; ok {
@@ -17,6 +22,11 @@ func InteractionCreate(s *discordgo.Session, i *discordgo.InteractionCreate, cmd
 		switch i.Type {
 		case discordgo.InteractionApplicationCommand:
 			err = cmd.Handler(s, i, state)
+			if err != nil {
+				if respondErr := respondError(s, i, err); respondErr != nil {
+					slog.Error("error failed to report error to user", "command", data.Name, "error", respondErr)
+				}
+			}
 		case discordgo.InteractionApplicationCommandAutocomplete:
 			if cmd.Autocomplete != nil {
 				err = cmd.Autocomplete(s, i, state)
@@ -29,6 +39,23 @@ func InteractionCreate(s *discordgo.Session, i *discordgo.InteractionCreate, cmd
 	}
 }
 
+func ComponentInteraction(s *discordgo.Session, i *discordgo.InteractionCreate, comps Components, state *state.State) {
+	data := i.MessageComponentData()
+	slog.Debug("Got component interaction", "id", data.CustomID)
+	comp, args, err := comps.lookup(data.CustomID)
+	if err == nil {
+		err = comp.Handler(s, i, state, args)
+	}
+	if err != nil {
+		slog.Error("error failed to complete component", "id", data.CustomID, "error", err)
+		if respondErr := respondError(s, i, err); respondErr != nil {
+			slog.Error("error failed to report error to user", "id", data.CustomID, "error", respondErr)
+		}
+	}
+}
+
+// MessageReactionRemove unmutes a trip when the 🤫 reaction is taken off an
+// alert. Reactions predate the alert buttons and are kept as a fallback.
 func MessageReactionRemove(s *discordgo.Session, r *discordgo.MessageReactionRemove, cmds Commands, st *state.State) {
 	if r.UserID == s.State.User.ID {
 		return
@@ -60,7 +87,13 @@ func MessageReactionRemove(s *discordgo.Session, r *discordgo.MessageReactionRem
 				slog.Error("error while getting trip", "error", err)
 				return
 			}
-			st.UnMuteTrip(trip.ID)
+			if err := st.UnMuteTrip(trip.ID); err != nil {
+				slog.Error("error while unmuting trip", "error", err)
+				if _, err := s.ChannelMessageSend(r.ChannelID, errorMessage(err)); err != nil {
+					slog.Error("error while sending msg", "error", err)
+				}
+				return
+			}
 			_, err = s.ChannelMessageSend(r.ChannelID, fmt.Sprintf("🔔 **%s unmuted.**", trip.Name))
 			if err != nil {
 				slog.Error("error while sending msg", "error", err)
@@ -101,7 +134,13 @@ func MessageReactionAdd(s *discordgo.Session, r *discordgo.MessageReactionAdd, c
 				slog.Error("error while getting trip", "error", err)
 				return
 			}
-			st.MuteTrip(trip.ID)
+			if err := st.MuteTrip(trip.ID); err != nil {
+				slog.Error("error while muting trip", "error", err)
+				if _, err := s.ChannelMessageSend(r.ChannelID, errorMessage(err)); err != nil {
+					slog.Error("error while sending msg", "error", err)
+				}
+				return
+			}
 			_, err = s.ChannelMessageSend(r.ChannelID, fmt.Sprintf("🔕 **%s muted.**", trip.Name))
 			if err != nil {
 				slog.Error("error while sending msg", "error", err)
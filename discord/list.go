@@ -4,10 +4,12 @@ import (
 	"fmt"
 	"log/slog"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/vincbro/pascal/blaise"
+	"github.com/vincbro/pascal/database"
 	"github.com/vincbro/pascal/state"
 	"github.com/vincbro/suddig"
 )
@@ -55,7 +57,21 @@ func listHandler(s *discordgo.Session, i *discordgo.InteractionCreate, st *state
 		fields = append(fields, &discordgo.MessageEmbedField{
 			Name:  "Schedule",
 			Value: trip.FormatSchedule(),
+		}, &discordgo.MessageEmbedField{
+			Name:  "Alerts",
+			Value: database.FormatAlerts(trip.AlertOffsets(user)),
 		})
+		if status := tripStatus(user, trip, userNow(st, user)); status != "" {
+			fields = append(fields, &discordgo.MessageEmbedField{
+				Name:  "Status",
+				Value: status,
+			})
+		} else if skips := st.Exceptions(user.ID).UpcomingSkips(trip, userNow(st, user), upcomingSkipDays); len(skips) > 0 {
+			fields = append(fields, &discordgo.MessageEmbedField{
+				Name:  "Upcoming skips",
+				Value: formatSkips(skips),
+			})
+		}
 		embed = &discordgo.MessageEmbed{
 			Title: trip.Name,
 			Description: fmt.Sprintf("From **%s** to **%s**\nDeparting **%s** and arriving **%s**\n(Travel time: %d min)",
@@ -82,21 +98,28 @@ func listHandler(s *discordgo.Session, i *discordgo.InteractionCreate, st *state
 		}
 
 		fields := make([]*discordgo.MessageEmbedField, 0, len(trips))
-		now := time.Now()
+		now := userNow(st, user)
+		exceptions := st.Exceptions(user.ID)
 		for _, trip := range trips {
-			if today && !trip.ShouldRun(now.Weekday()) {
+			if today && !trip.RunsOn(now) {
 				continue
 			}
+			value := fmt.Sprintf("From **%s** to **%s**\nDeparting **%s** and arriving **%s**\n(Travel time: %d min)\n**Schedule**:\n%s",
+				trip.From,
+				trip.To,
+				trip.ExpectedItinerary.DepartureTime.ToHMSString(),
+				trip.ExpectedItinerary.ArrivalTime.ToHMSString(),
+				(trip.ExpectedItinerary.ArrivalTime-trip.ExpectedItinerary.DepartureTime)/60,
+				trip.FormatSchedule(),
+			)
+			if status := tripStatus(user, trip, now); status != "" {
+				value += "\n" + status
+			} else if skips := exceptions.UpcomingSkips(trip, now, upcomingSkipDays); len(skips) > 0 {
+				value += fmt.Sprintf("\n**Upcoming skips**:\n%s", formatSkips(skips))
+			}
 			fields = append(fields, &discordgo.MessageEmbedField{
-				Name: trip.Name,
-				Value: fmt.Sprintf("From **%s** to **%s**\nDeparting **%s** and arriving **%s**\n(Travel time: %d min)\n**Schedule**:\n%s",
-					trip.From,
-					trip.To,
-					trip.ExpectedItinerary.DepartureTime.ToHMSString(),
-					trip.ExpectedItinerary.ArrivalTime.ToHMSString(),
-					(trip.ExpectedItinerary.ArrivalTime-trip.ExpectedItinerary.DepartureTime)/60,
-					trip.FormatSchedule(),
-				),
+				Name:  trip.Name,
+				Value: value,
 			})
 		}
 
@@ -108,6 +131,9 @@ func listHandler(s *discordgo.Session, i *discordgo.InteractionCreate, st *state
 		} else {
 			desc = "Here is all the trips you have registered"
 		}
+		if user.Vacation {
+			desc += "\n🏝️ You are on vacation, turn it off with /vacation"
+		}
 		// 4. Create the Embed
 		embed = &discordgo.MessageEmbed{
 			Title:       "Your trips",
@@ -130,6 +156,28 @@ func listHandler(s *discordgo.Session, i *discordgo.InteractionCreate, st *state
 	return err
 }
 
+// tripStatus tells why none of the trip's alerts are sent, if they aren't.
+func tripStatus(user *database.User, trip *database.Trip, now time.Time) string {
+	if user.Vacation {
+		return "🏝️ **On vacation**"
+	}
+	if !trip.PausedOn(now) {
+		return ""
+	}
+	return trip.FormatPaused()
+}
+
+// upcomingSkipDays is how far ahead /list looks for skipped days.
+const upcomingSkipDays = 14
+
+func formatSkips(skips []state.Skip) string {
+	lines := make([]string, len(skips))
+	for i, skip := range skips {
+		lines[i] = fmt.Sprintf("• %s (%s)", skip.Date.Format("Monday 2 January"), skip.Reason)
+	}
+	return strings.Join(lines, "\n")
+}
+
 func listAutocomplete(s *discordgo.Session, i *discordgo.InteractionCreate, state *state.State) error {
 	user, err := GetUser(i.User, i.ChannelID, state)
 	if err != nil {
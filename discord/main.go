@@ -1,16 +1,18 @@
 package main
 
 import (
-	"fmt"
 	"log/slog"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/joho/godotenv"
 	"github.com/vincbro/pascal/blaise"
+	"github.com/vincbro/pascal/calendar"
 	"github.com/vincbro/pascal/database"
+	"github.com/vincbro/pascal/realtime"
 	"github.com/vincbro/pascal/state"
 )
 
@@ -31,6 +33,9 @@ func main() {
 	if dbPath == "" {
 		dbPath = "main.db"
 	}
+	holidaysPath := os.Getenv("HOLIDAYS_PATH")
+	recheckMinutes := os.Getenv("RECHECK_MINUTES")
+	realtimeFeeds := os.Getenv("GTFS_RT_FEEDS")
 	slog.Debug("Settings: ", "Guild ID", guildID)
 
 	slog.Info("Creating blaise client")
@@ -42,6 +47,34 @@ func main() {
 		os.Exit(2)
 	}
 	st := state.NewState(db, bClient, gtfsUrl)
+	if holidaysPath != "" {
+		slog.Info("Loading holidays", "path", holidaysPath)
+		st.Calendar, err = calendar.Load(holidaysPath)
+		if err != nil {
+			slog.Error("error loading holidays", "error", err)
+			os.Exit(2)
+		}
+		slog.Info("Loaded holidays", "days", st.Calendar.Len(), "recurring", st.Calendar.Recurring())
+	}
+	if realtimeFeeds != "" {
+		sources := strings.Split(realtimeFeeds, ",")
+		for i := range sources {
+			sources[i] = strings.TrimSpace(sources[i])
+		}
+		slog.Info("Using realtime feeds", "feeds", sources)
+		st.Realtime = realtime.NewClient(sources...)
+	}
+	switch recheckMinutes {
+	case "", "default":
+	case "off":
+		st.Rechecks = nil
+	default:
+		st.Rechecks, err = database.ParseAlerts(recheckMinutes)
+		if err != nil {
+			slog.Error("error parsing RECHECK_MINUTES", "error", err)
+			os.Exit(2)
+		}
+	}
 
 	slog.Info("Created discord session")
 	dg, err := discordgo.New("Bot " + discordKey)
@@ -57,8 +90,9 @@ func main() {
 		slog.Error("error creating commands", "error", err)
 		os.Exit(4)
 	}
+	comps := GetComponents()
 	dg.AddHandler(func(s *discordgo.Session, i *discordgo.InteractionCreate) {
-		InteractionCreate(s, i, cmds, st)
+		InteractionCreate(s, i, cmds, comps, st)
 	})
 	dg.AddHandler(func(s *discordgo.Session, r *discordgo.MessageReactionAdd) {
 		MessageReactionAdd(s, r, cmds, st)
@@ -68,26 +102,7 @@ func main() {
 	})
 
 	st.AddHandler(func(s *state.State, request state.Request) error {
-		user, err := s.DB.GetUser(request.UserID)
-		if err != nil {
-			return err
-		}
-		trip, err := s.DB.GetTrip(request.UserID, request.TripID)
-		if err != nil {
-			return err
-		}
-		embed := &discordgo.MessageEmbed{
-			Title:  request.Message,
-			Fields: blaise.IteniraryToEmbedFields(trip.ExpectedItinerary),
-			Footer: &discordgo.MessageEmbedFooter{
-				Text: fmt.Sprintf("Pascal • TripID: %s", trip.ID),
-			},
-		}
-		msg, err := dg.ChannelMessageSendEmbed(user.ChannelID, embed)
-		if err != nil {
-			return err
-		}
-		return dg.MessageReactionAdd(msg.ChannelID, msg.ID, "🤫")
+		return notify(dg, s, request)
 	})
 
 	slog.Info("Opening discord bot connection")
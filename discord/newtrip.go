@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/google/uuid"
+
+	"github.com/vincbro/pascal/nlp"
+	"github.com/vincbro/pascal/state"
+)
+
+func CreateNewTripCommand() Command {
+	return Command{
+		Definition: &discordgo.ApplicationCommand{
+			Name:        "new",
+			Description: "Create a new trip by describing it",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "trip",
+					Description: "e.g. Work, from Home to Office every weekday, arrive by 08:00",
+					Type:        discordgo.ApplicationCommandOptionString,
+					Required:    true,
+				},
+			},
+		},
+		Handler: newTripHandler,
+	}
+}
+
+func newTripHandler(s *discordgo.Session, i *discordgo.InteractionCreate, state *state.State) error {
+	user, err := GetUser(i.User, i.ChannelID, state)
+	if err != nil {
+		return err
+	}
+	opts := ParseOptions(i.ApplicationCommandData().Options)
+
+	parsed, err := nlp.Parse(opts["trip"].StringValue(), userNow(state, user))
+	if err != nil {
+		return err
+	}
+	places, err := state.DB.GetPlaces(user.ID)
+	if err != nil {
+		return err
+	}
+	trip, err := parsed.Trip(context.Background(), state.BClient, user, places)
+	if err != nil {
+		return err
+	}
+	trip.ID = uuid.New().String()
+
+	iteniraries, err := state.BClient.Routing(context.Background(), trip.FromID, trip.ToID, trip.Time, trip.Departure, 1)
+	if err != nil {
+		return err
+	}
+	itenirary := iteniraries[0]
+	draftID := drafts.put(tripDraft{trip: trip, options: iteniraries}, state.Clock.Now())
+
+	scheduleType := "Depart at"
+	if !trip.Departure {
+		scheduleType = "Arrive by"
+	}
+	embed := &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("📝 New trip: %s", trip.Name),
+		Description: "Is this what you meant? Nothing is saved until you confirm.",
+		Color:       0x5865F2,
+		Fields: []*discordgo.MessageEmbedField{
+			{
+				Name:   "Route",
+				Value:  fmt.Sprintf("From: **%s**\nTo: **%s**", trip.From, trip.To),
+				Inline: true,
+			},
+			{
+				Name:   "Schedule",
+				Value:  fmt.Sprintf("%s **%s**\n%s", scheduleType, trip.Time, trip.FormatSchedule()),
+				Inline: true,
+			},
+			{
+				Name: "Possible trips",
+				Value: fmt.Sprintf("Found one departing **%s** and arriving **%s**\n(Travel time: %d min)",
+					itenirary.DepartureTime.ToHMSString(),
+					itenirary.ArrivalTime.ToHMSString(),
+					(itenirary.ArrivalTime-itenirary.DepartureTime)/60,
+				),
+				Inline: false,
+			},
+		},
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: "Pascal • Watching your commute",
+		},
+	}
+
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{embed},
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.Button{
+							Label:    "Save",
+							Style:    discordgo.SuccessButton,
+							Emoji:    &discordgo.ComponentEmoji{Name: "✅"},
+							CustomID: ComponentID("new_confirm", draftID),
+						},
+						discordgo.Button{
+							Label:    "Cancel",
+							Style:    discordgo.SecondaryButton,
+							CustomID: ComponentID("new_cancel", draftID),
+						},
+					},
+				},
+			},
+		},
+	})
+
+	return err
+}
+
+func newTripConfirmHandler(s *discordgo.Session, i *discordgo.InteractionCreate, state *state.State, args []string) error {
+	user, err := GetUser(i.User, i.ChannelID, state)
+	if err != nil {
+		return err
+	}
+	draft, ok, err := getDraft(s, i, state, user, args[0], "new")
+	if !ok {
+		return err
+	}
+
+	embed, err := saveTrip(state, user, &draft.trip, draft.options[0])
+	if err != nil {
+		return err
+	}
+	drafts.take(args[0], state.Clock.Now())
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     []*discordgo.MessageEmbed{embed},
+			Components: []discordgo.MessageComponent{},
+		},
+	})
+}
+
+func newTripCancelHandler(s *discordgo.Session, i *discordgo.InteractionCreate, state *state.State, args []string) error {
+	drafts.take(args[0], state.Clock.Now())
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    "👍 Okay, I didn't save it.",
+			Embeds:     []*discordgo.MessageEmbed{},
+			Components: []discordgo.MessageComponent{},
+		},
+	})
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/vincbro/pascal/blaise"
+	"github.com/vincbro/pascal/state"
+)
+
+// notify delivers a request from the trip watcher to the user. With live
+// alerts on, the departure alerts of a trip share one message that is edited
+// in place and closed once the trip has left.
+func notify(dg *discordgo.Session, s *state.State, request state.Request) error {
+	user, err := s.DB.GetUser(request.UserID)
+	if err != nil {
+		return err
+	}
+	trip := request.Trip
+	if trip == nil {
+		trip, err = s.DB.GetTrip(request.UserID, request.TripID)
+		if err != nil {
+			return err
+		}
+	}
+	embed := &discordgo.MessageEmbed{
+		Title: request.Message,
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf("Pascal • TripID: %s", trip.ID),
+		},
+	}
+	// Only the pre-departure alerts carry the whole route, once underway the
+	// title says all there is to say
+	if request.Kind == state.RequestDepartSoon || request.Kind == state.RequestTripChanged {
+		embed.Fields = blaise.IteniraryToEmbedFields(trip.ExpectedItinerary)
+	}
+	if len(request.Changes) > 0 {
+		embed.Description = strings.Join(request.Changes, "\n")
+		embed.Color = 0xFEE75C
+	}
+	components := alertComponents(request)
+
+	live := user.LiveAlerts && (request.Kind == state.RequestDepartSoon || request.Kind == state.RequestDeparted)
+	if live && request.MessageID != "" {
+		edit := discordgo.NewMessageEdit(user.ChannelID, request.MessageID)
+		edit.Embeds = &[]*discordgo.MessageEmbed{embed}
+		edit.Components = &components
+		_, err := dg.ChannelMessageEditComplex(edit)
+		if err == nil {
+			return nil
+		}
+		// The message might have been deleted, a new one is better than nothing
+		slog.Warn("failed to edit live alert, sending a new one", "trip", trip.ID, "error", err)
+	}
+	if request.Kind == state.RequestDeparted {
+		return nil
+	}
+
+	msg, err := dg.ChannelMessageSendComplex(user.ChannelID, &discordgo.MessageSend{
+		Embeds:     []*discordgo.MessageEmbed{embed},
+		Components: components,
+	})
+	if err != nil {
+		return err
+	}
+	if live {
+		s.SetAlertMessage(trip.ID, request.ServiceDate, msg.ID)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/vincbro/pascal/state"
+)
+
+func CreatePauseCommand() Command {
+	return Command{
+		Definition: &discordgo.ApplicationCommand{
+			Name:        "pause",
+			Description: "Stop the alerts of a trip until you resume it",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:         "name",
+					Description:  "The trip to pause",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Required:     true,
+					Autocomplete: true,
+				},
+				{
+					Name:         "until",
+					Description:  "The last day to pause it (YYYY-MM-DD), leave empty to pause until resumed",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Autocomplete: true,
+				},
+			},
+		},
+		Handler:      pauseHandler,
+		Autocomplete: skipAutocomplete,
+	}
+}
+
+func CreateResumeCommand() Command {
+	return Command{
+		Definition: &discordgo.ApplicationCommand{
+			Name:        "resume",
+			Description: "Start alerting for a paused trip again",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:         "name",
+					Description:  "The trip to resume",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Required:     true,
+					Autocomplete: true,
+				},
+			},
+		},
+		Handler:      resumeHandler,
+		Autocomplete: listAutocomplete,
+	}
+}
+
+func CreateVacationCommand() Command {
+	return Command{
+		Definition: &discordgo.ApplicationCommand{
+			Name:        "vacation",
+			Description: "Pause all your trips until you are back",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "enabled",
+					Description: "Turn vacation mode on or off, leave empty to toggle it",
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+				},
+			},
+		},
+		Handler: vacationHandler,
+	}
+}
+
+func pauseHandler(s *discordgo.Session, i *discordgo.InteractionCreate, state *state.State) error {
+	user, err := GetUser(i.User, i.ChannelID, state)
+	if err != nil {
+		return err
+	}
+	opts := ParseOptions(i.ApplicationCommandData().Options)
+
+	trip, err := state.DB.GetTrip(user.ID, opts["name"].StringValue())
+	if err != nil {
+		return err
+	}
+	until := ""
+	if val, ok := opts["until"]; ok {
+		until, err = parseDate(val.StringValue(), userNow(state, user))
+		if err != nil {
+			return err
+		}
+	}
+	trip.Paused = true
+	trip.PausedUntil = until
+	if err = state.DB.UpdateTrip(trip); err != nil {
+		return err
+	}
+	state.ScheduleTrip(trip)
+
+	return respondTripStatus(s, i, fmt.Sprintf("⏸️ Paused: %s", trip.Name), trip.FormatPaused()+", resume it any time with /resume.")
+}
+
+func resumeHandler(s *discordgo.Session, i *discordgo.InteractionCreate, state *state.State) error {
+	user, err := GetUser(i.User, i.ChannelID, state)
+	if err != nil {
+		return err
+	}
+	opts := ParseOptions(i.ApplicationCommandData().Options)
+
+	trip, err := state.DB.GetTrip(user.ID, opts["name"].StringValue())
+	if err != nil {
+		return err
+	}
+	if !trip.Paused {
+		return fmt.Errorf("%s isn't paused", trip.Name)
+	}
+	trip.Paused = false
+	trip.PausedUntil = ""
+	if err = state.DB.UpdateTrip(trip); err != nil {
+		return err
+	}
+	state.ScheduleTrip(trip)
+
+	desc := "I'll alert you for this trip again."
+	if user.Vacation {
+		desc = "I'll alert you for this trip again once you are back from vacation."
+	}
+	return respondTripStatus(s, i, fmt.Sprintf("▶️ Resumed: %s", trip.Name), desc)
+}
+
+func vacationHandler(s *discordgo.Session, i *discordgo.InteractionCreate, state *state.State) error {
+	user, err := GetUser(i.User, i.ChannelID, state)
+	if err != nil {
+		return err
+	}
+	opts := ParseOptions(i.ApplicationCommandData().Options)
+
+	user.Vacation = !user.Vacation
+	if val, ok := opts["enabled"]; ok {
+		user.Vacation = val.BoolValue()
+	}
+	if err = state.DB.UpdateUser(user); err != nil {
+		return err
+	}
+
+	if user.Vacation {
+		return respondTripStatus(s, i, "🏝️ Vacation mode on", "I won't alert you for any of your trips until you turn it off with /vacation.")
+	}
+	return respondTripStatus(s, i, "🏙️ Vacation mode off", "Welcome back! Your trips run as usual again.")
+}
+
+func respondTripStatus(s *discordgo.Session, i *discordgo.InteractionCreate, title string, description string) error {
+	embed := &discordgo.MessageEmbed{
+		Title:       title,
+		Description: description,
+		Color:       0x57F287,
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: "Pascal • Watching your commute",
+		},
+	}
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{embed},
+		},
+	})
+}
@@ -0,0 +1,332 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/vincbro/pascal/blaise"
+	"github.com/vincbro/pascal/database"
+	"github.com/vincbro/pascal/state"
+)
+
+func CreatePlaceCommand() Command {
+	return Command{
+		Definition: &discordgo.ApplicationCommand{
+			Name:        "place",
+			Description: "Give the places you often travel to a name, like Home or Work",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "add",
+					Description: "Save a place under a name",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:        "name",
+							Description: "The name to use for the place, e.g. Home",
+							Type:        discordgo.ApplicationCommandOptionString,
+							Required:    true,
+						},
+						{
+							Name:         "location",
+							Description:  "The stop or area",
+							Type:         discordgo.ApplicationCommandOptionString,
+							Required:     true,
+							Autocomplete: true,
+						},
+					},
+				},
+				{
+					Name:        "remove",
+					Description: "Forget a saved place",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:         "name",
+							Description:  "The place to forget",
+							Type:         discordgo.ApplicationCommandOptionString,
+							Required:     true,
+							Autocomplete: true,
+						},
+					},
+				},
+				{
+					Name:        "list",
+					Description: "List your saved places",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+				},
+			},
+		},
+		Handler:      placeHandler,
+		Autocomplete: placeAutocomplete,
+	}
+}
+
+func placeHandler(s *discordgo.Session, i *discordgo.InteractionCreate, state *state.State) error {
+	user, err := GetUser(i.User, i.ChannelID, state)
+	if err != nil {
+		return err
+	}
+	sub := i.ApplicationCommandData().Options[0]
+	opts := ParseOptions(sub.Options)
+
+	var embed *discordgo.MessageEmbed
+	switch sub.Name {
+	case "add":
+		alias := strings.TrimSpace(opts["name"].StringValue())
+		if alias == "" {
+			return fmt.Errorf("the name of a place can't be empty")
+		}
+		location, err := lookupLocation(state, user, opts["location"].StringValue())
+		if err != nil {
+			return err
+		}
+		if err = state.DB.SavePlace(&database.Place{UserID: user.ID, Alias: alias, Location: location}); err != nil {
+			return err
+		}
+
+		embed = &discordgo.MessageEmbed{
+			Title:       fmt.Sprintf("📍 Saved: %s", alias),
+			Description: fmt.Sprintf("**%s** now means **%s**, use it as from or to in any command.", alias, location.Name),
+			Color:       0x57F287,
+		}
+	case "remove":
+		alias := opts["name"].StringValue()
+		if err = state.DB.RemovePlace(user.ID, alias); err != nil {
+			return err
+		}
+
+		embed = &discordgo.MessageEmbed{
+			Title:       fmt.Sprintf("🗑️ Removed: %s", alias),
+			Description: "Your trips are not affected.",
+			Color:       0x57F287,
+		}
+	case "list":
+		places, err := state.DB.GetPlaces(user.ID)
+		if err != nil {
+			return err
+		}
+		lines := make([]string, 0, len(places))
+		for _, place := range places {
+			lines = append(lines, fmt.Sprintf("📍 **%s**: %s", place.Alias, place.Location.Name))
+		}
+		desc := "You don't have any saved places, you can add some with /place add"
+		if len(lines) > 0 {
+			desc = strings.Join(lines, "\n")
+		}
+
+		embed = &discordgo.MessageEmbed{
+			Title:       "Your places",
+			Description: desc,
+			Color:       0x57F287,
+		}
+	}
+	embed.Footer = &discordgo.MessageEmbedFooter{
+		Text: "Pascal • Watching your commute",
+	}
+
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{embed},
+		},
+	})
+
+	return err
+}
+
+func placeAutocomplete(s *discordgo.Session, i *discordgo.InteractionCreate, state *state.State) error {
+	user, err := GetUser(i.User, i.ChannelID, state)
+	if err != nil {
+		return err
+	}
+	sub := i.ApplicationCommandData().Options[0]
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, 20)
+
+	for _, option := range sub.Options {
+		if !option.Focused {
+			continue
+		}
+		switch option.Name {
+		case "location":
+			choices, err = locationChoices(state, user, option.StringValue())
+			if err != nil {
+				return err
+			}
+		case "name":
+			for _, place := range matchPlaces(state, user, option.StringValue()) {
+				choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+					Name:  fmt.Sprintf("📍 %s • %s", place.Alias, place.Location.Name),
+					Value: place.Alias,
+				})
+			}
+		}
+	}
+
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{
+			Choices: choices,
+		},
+	})
+}
+
+// matchPlaces returns the saved places whose alias contains input.
+func matchPlaces(state *state.State, user *database.User, input string) []*database.Place {
+	places, err := state.DB.GetPlaces(user.ID)
+	if err != nil {
+		slog.Error("error failed to get places for user", "user", user.ID, "error", err)
+		return nil
+	}
+	matches := make([]*database.Place, 0, len(places))
+	for _, place := range places {
+		if strings.Contains(strings.ToLower(place.Alias), strings.ToLower(strings.TrimSpace(input))) {
+			matches = append(matches, place)
+		}
+	}
+	return matches
+}
+
+// placeChoices suggests the saved places matching input, their value is the
+// id of the location so they work as from and to.
+func placeChoices(state *state.State, user *database.User, input string) []*discordgo.ApplicationCommandOptionChoice {
+	places := matchPlaces(state, user, input)
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(places))
+	for _, place := range places {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  fmt.Sprintf("📍 %s • %s", place.Alias, place.Location.Name),
+			Value: place.Location.ID,
+		})
+	}
+	return choices
+}
+
+// maxChoices is the most choices Discord takes in an autocomplete response.
+const maxChoices = 25
+
+// locationChoices suggests the saved places matching input, then the history
+// when input is empty or what a search for it finds. Every value is a
+// location id, so stops sharing a name can't be mixed up, and search results
+// are remembered for lookupLocation to find again.
+func locationChoices(state *state.State, user *database.User, input string) ([]*discordgo.ApplicationCommandOptionChoice, error) {
+	choices := placeChoices(state, user, input)
+	results := user.Locations
+	if strings.TrimSpace(input) != "" {
+		var err error
+		results, err = state.BClient.SearchAreas(context.Background(), input, 10)
+		if err != nil {
+			return nil, err
+		}
+		searched.put(results, state.Clock.Now())
+	}
+	for _, area := range results {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  area.Name,
+			Value: area.ID,
+		})
+	}
+	return choices[:min(len(choices), maxChoices)], nil
+}
+
+// searchedTTL is how long the locations offered by autocomplete are
+// remembered, plenty of time to pick one and send the command.
+const searchedTTL = 15 * time.Minute
+
+type searchedLocation struct {
+	location blaise.Location
+	expires  time.Time
+}
+
+// locationCache remembers locations by id. Autocomplete only hands back the
+// id of the picked choice and blaise can't look a location up by id.
+type locationCache struct {
+	mu        sync.Mutex
+	locations map[string]searchedLocation
+}
+
+var searched = &locationCache{locations: make(map[string]searchedLocation)}
+
+func (c *locationCache) put(locations []blaise.Location, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id, old := range c.locations {
+		if now.After(old.expires) {
+			delete(c.locations, id)
+		}
+	}
+	for _, loc := range locations {
+		c.locations[loc.ID] = searchedLocation{location: loc, expires: now.Add(searchedTTL)}
+	}
+}
+
+// get returns the location with the id, if it was searched for recently.
+func (c *locationCache) get(id string, now time.Time) (blaise.Location, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	searched, ok := c.locations[id]
+	if !ok || now.After(searched.expires) {
+		return blaise.Location{}, false
+	}
+	return searched.location, true
+}
+
+// resolvePlace turns a from or to option into a location id, replacing the
+// alias of a saved place with its location. Anything else is passed on as is.
+func resolvePlace(state *state.State, user *database.User, input string) string {
+	place, err := state.DB.GetPlace(user.ID, strings.TrimSpace(input))
+	if err != nil {
+		return input
+	}
+	return place.Location.ID
+}
+
+// lookupLocation finds the location a user picked or typed. A picked choice
+// is the id of a saved place, the history or a recent search, anything else
+// is an alias or a name to search for.
+func lookupLocation(state *state.State, user *database.User, input string) (blaise.Location, error) {
+	input = strings.TrimSpace(input)
+	if place, err := state.DB.GetPlace(user.ID, input); err == nil {
+		return place.Location, nil
+	}
+	places, err := state.DB.GetPlaces(user.ID)
+	if err != nil {
+		return blaise.Location{}, err
+	}
+	for _, place := range places {
+		if place.Location.ID == input {
+			return place.Location, nil
+		}
+	}
+	for _, loc := range user.Locations {
+		if loc.ID == input {
+			return loc, nil
+		}
+	}
+	if loc, ok := searched.get(input, state.Clock.Now()); ok {
+		return loc, nil
+	}
+
+	for _, loc := range user.Locations {
+		if strings.EqualFold(loc.Name, input) {
+			return loc, nil
+		}
+	}
+	results, err := state.BClient.SearchAreas(context.Background(), input, 10)
+	if err != nil {
+		return blaise.Location{}, err
+	}
+	for _, loc := range results {
+		if strings.EqualFold(loc.Name, input) {
+			return loc, nil
+		}
+	}
+	if len(results) == 0 {
+		return blaise.Location{}, fmt.Errorf("%w: %q", blaise.ErrUnknownLocation, input)
+	}
+	return results[0], nil
+}
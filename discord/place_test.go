@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/vincbro/pascal/blaise"
+	"github.com/vincbro/pascal/blaise/blaisetest"
+	"github.com/vincbro/pascal/database"
+)
+
+// Two towns with a stop called Torget.
+const twoTorgets = `[
+  {"id": "740000100", "type": "area", "name": "Torget", "coordinate": {"latitude": 59.37, "longitude": 17.83}},
+  {"id": "740000200", "type": "area", "name": "Torget", "coordinate": {"latitude": 59.62, "longitude": 17.72}}
+]`
+
+func TestLookupPickedLocation(t *testing.T) {
+	s, clock, user := newTestState(t, time.Date(2026, 10, 19, 7, 0, 0, 0, time.UTC))
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "areas.json"), []byte(twoTorgets), 0o644); err != nil {
+		t.Fatalf("failed to write areas: %v", err)
+	}
+	server := blaisetest.NewServer()
+	t.Cleanup(server.Close)
+	if err := server.LoadFixtures(dir); err != nil {
+		t.Fatalf("failed to load areas: %v", err)
+	}
+	s.BClient = server.Client()
+
+	choices, err := locationChoices(s, user, "torget")
+	if err != nil {
+		t.Fatalf("failed to get choices: %v", err)
+	}
+	if len(choices) != 2 || choices[0].Value != "740000100" || choices[1].Value != "740000200" {
+		t.Fatalf("got choices %+v, want both stops by id", choices)
+	}
+	// Each pick finds its own stop, not the first one with the name
+	for _, choice := range choices {
+		loc, err := lookupLocation(s, user, choice.Value.(string))
+		if err != nil || loc.ID != choice.Value || loc.Name != "Torget" {
+			t.Errorf("picking %v gave %+v, %v", choice.Value, loc, err)
+		}
+	}
+
+	// A saved place and the history resolve by id too
+	home := blaise.Location{ID: odenplan, Name: "Odenplan"}
+	if err := s.DB.SavePlace(&database.Place{UserID: user.ID, Alias: "Home", Location: home}); err != nil {
+		t.Fatalf("failed to save place: %v", err)
+	}
+	user.AddHistory(blaise.Location{ID: tekniska, Name: "Tekniska högskolan"})
+	for _, input := range []string{"Home", odenplan, tekniska, "Tekniska högskolan"} {
+		if _, err := lookupLocation(s, user, input); err != nil {
+			t.Errorf("failed to look up %q: %v", input, err)
+		}
+	}
+
+	// A stale pick can't be told apart from a name any more
+	clock.Advance(searchedTTL + time.Minute)
+	if loc, err := lookupLocation(s, user, "740000200"); err == nil {
+		t.Errorf("an expired pick resolved to %+v", loc)
+	}
+}
+
+func TestLocationChoicesLimit(t *testing.T) {
+	s, _, user := newTestState(t, time.Date(2026, 10, 19, 7, 0, 0, 0, time.UTC))
+	for i := range maxChoices + 5 {
+		place := &database.Place{UserID: user.ID, Alias: "Place " + string(rune('A'+i)), Location: blaise.Location{ID: odenplan, Name: "Odenplan"}}
+		if err := s.DB.SavePlace(place); err != nil {
+			t.Fatalf("failed to save place: %v", err)
+		}
+	}
+	choices, err := locationChoices(s, user, "")
+	if err != nil {
+		t.Fatalf("failed to get choices: %v", err)
+	}
+	if len(choices) != maxChoices {
+		t.Errorf("got %d choices, want at most %d", len(choices), maxChoices)
+	}
+}
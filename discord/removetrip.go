@@ -55,6 +55,7 @@ func removeTripHandler(s *discordgo.Session, i *discordgo.InteractionCreate, sta
 	if err := state.DB.RemoveTrip(user.ID, tripID); err != nil {
 		return err
 	}
+	state.UnscheduleTrip(tripID)
 
 	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
 		Type: discordgo.InteractionResponseChannelMessageWithSource,
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/google/uuid"
+
+	"github.com/vincbro/pascal/blaise"
+	"github.com/vincbro/pascal/database"
+	"github.com/vincbro/pascal/state"
+)
+
+func CreateRouteCommand() Command {
+	return Command{
+		Definition: &discordgo.ApplicationCommand{
+			Name:        "route",
+			Description: "Plan a single journey without saving it",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:         "from",
+					Description:  "Where you are leaving from",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Required:     true,
+					Autocomplete: true,
+				},
+				{
+					Name:         "to",
+					Description:  "Where you are going",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Required:     true,
+					Autocomplete: true,
+				},
+				{
+					Name:        "type",
+					Description: "Is this the time you want to leave or the time you want to arrive? Defaults to leave",
+					Type:        discordgo.ApplicationCommandOptionString,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "Arrive By", Value: "arrive"},
+						{Name: "Depart At", Value: "depart"},
+					},
+				},
+				{
+					Name:         "time",
+					Description:  "The time you want to departe or arrive at, defaults to now",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Autocomplete: true,
+				},
+			},
+		},
+		Handler:      routeHandler,
+		Autocomplete: addTripAutocomplete,
+	}
+}
+
+func routeHandler(s *discordgo.Session, i *discordgo.InteractionCreate, state *state.State) error {
+	user, err := GetUser(i.User, i.ChannelID, state)
+	if err != nil {
+		return err
+	}
+	opts := ParseOptions(i.ApplicationCommandData().Options)
+
+	from := resolvePlace(state, user, opts["from"].StringValue())
+	to := resolvePlace(state, user, opts["to"].StringValue())
+	departure := true
+	if val, ok := opts["type"]; ok {
+		departure = val.StringValue() == "depart"
+	}
+	time := userNow(state, user).Format("15:04:05")
+	if val, ok := opts["time"]; ok {
+		time = val.StringValue()
+	}
+
+	iteniraries, err := state.BClient.Routing(context.Background(), from, to, time, departure, 1)
+	if err != nil {
+		return err
+	}
+	itenirary := iteniraries[0]
+
+	user.AddHistory(itenirary.From)
+	user.AddHistory(itenirary.To)
+	if err = state.DB.UpdateUser(user); err != nil {
+		return err
+	}
+
+	// Nothing is stored unless one of the buttons is used
+	trip := database.Trip{
+		ID:        uuid.New().String(),
+		UserID:    user.ID,
+		Name:      fmt.Sprintf("%s ➔ %s", itenirary.From.Name, itenirary.To.Name),
+		Time:      time,
+		Departure: departure,
+
+		Recurrence: database.Recurrence{Freq: database.Daily, Interval: 1},
+	}
+	draftID := drafts.put(tripDraft{trip: trip, options: iteniraries}, state.Clock.Now())
+
+	embed := &discordgo.MessageEmbed{
+		Title: fmt.Sprintf("🧭 %s", trip.Name),
+		Description: fmt.Sprintf("Departing **%s** and arriving **%s**\n(Travel time: %d min)",
+			itenirary.DepartureTime.ToHMSString(),
+			itenirary.ArrivalTime.ToHMSString(),
+			(itenirary.ArrivalTime-itenirary.DepartureTime)/60,
+		),
+		Color:  0x5865F2,
+		Fields: blaise.IteniraryToEmbedFields(itenirary),
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: "Pascal • Watching your commute",
+		},
+	}
+
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{embed},
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.Button{
+							Label:    "Save as trip",
+							Style:    discordgo.PrimaryButton,
+							Emoji:    &discordgo.ComponentEmoji{Name: "💾"},
+							CustomID: ComponentID("route_save", draftID),
+						},
+						discordgo.Button{
+							Label:    "Remind me once",
+							Style:    discordgo.SecondaryButton,
+							Emoji:    &discordgo.ComponentEmoji{Name: "⏰"},
+							CustomID: ComponentID("route_remind", draftID),
+						},
+					},
+				},
+			},
+		},
+	})
+
+	return err
+}
+
+// getDraft returns the draft behind a button, or answers the interaction
+// itself if it has expired and the command has to be run again. The draft
+// is left in place, callers take it once the trip is saved.
+func getDraft(s *discordgo.Session, i *discordgo.InteractionCreate, state *state.State, user *database.User, draftID string, command string) (tripDraft, bool, error) {
+	draft, ok := drafts.get(draftID, state.Clock.Now())
+	if ok && draft.trip.UserID == user.ID {
+		return draft, true, nil
+	}
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    fmt.Sprintf("⌛ This has expired, run /%s again.", command),
+			Components: []discordgo.MessageComponent{},
+		},
+	})
+	return tripDraft{}, false, err
+}
+
+func routeSaveHandler(s *discordgo.Session, i *discordgo.InteractionCreate, state *state.State, args []string) error {
+	user, err := GetUser(i.User, i.ChannelID, state)
+	if err != nil {
+		return err
+	}
+	draft, ok, err := getDraft(s, i, state, user, args[0], "route")
+	if !ok {
+		return err
+	}
+
+	embed, err := saveTrip(state, user, &draft.trip, draft.options[0])
+	if err != nil {
+		return err
+	}
+	drafts.take(args[0], state.Clock.Now())
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     []*discordgo.MessageEmbed{embed},
+			Components: []discordgo.MessageComponent{},
+		},
+	})
+}
+
+func routeRemindHandler(s *discordgo.Session, i *discordgo.InteractionCreate, state *state.State, args []string) error {
+	user, err := GetUser(i.User, i.ChannelID, state)
+	if err != nil {
+		return err
+	}
+	draft, ok, err := getDraft(s, i, state, user, args[0], "route")
+	if !ok {
+		return err
+	}
+
+	// A reminder is a trip dated today, it removes itself once it has arrived
+	trip := draft.trip
+	trip.Date = userNow(state, user).Format(database.DateLayout)
+	trip.Name = fmt.Sprintf("Reminder: %s", trip.Name)
+	if _, err := saveTrip(state, user, &trip, draft.options[0]); err != nil {
+		return err
+	}
+	drafts.take(args[0], state.Clock.Now())
+
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("⏰ I'll remind you before the **%s** departure, %s.",
+				trip.ExpectedItinerary.DepartureTime.ToHMSString(),
+				database.FormatAlerts(trip.AlertOffsets(user)),
+			),
+			Components: []discordgo.MessageComponent{},
+		},
+	})
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/vincbro/pascal/database"
+	"github.com/vincbro/pascal/state"
+)
+
+func CreateSettingsCommand() Command {
+	return Command{
+		Definition: &discordgo.ApplicationCommand{
+			Name:        "settings",
+			Description: "Show or change how Pascal alerts you",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "live_alerts",
+					Description: "Keep one message per trip up to date instead of posting every alert",
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+				},
+				{
+					Name:        "time_zone",
+					Description: "The time zone your trips run in (e.g. Europe/Stockholm)",
+					Type:        discordgo.ApplicationCommandOptionString,
+				},
+			},
+		},
+		Handler: settingsHandler,
+	}
+}
+
+func settingsHandler(s *discordgo.Session, i *discordgo.InteractionCreate, state *state.State) error {
+	user, err := GetUser(i.User, i.ChannelID, state)
+	if err != nil {
+		return err
+	}
+	opts := ParseOptions(i.ApplicationCommandData().Options)
+
+	title := "⚙️ Your settings"
+	if len(opts) > 0 {
+		if val, ok := opts["live_alerts"]; ok {
+			user.LiveAlerts = val.BoolValue()
+		}
+		zoneChanged := false
+		if val, ok := opts["time_zone"]; ok {
+			loc, err := time.LoadLocation(strings.TrimSpace(val.StringValue()))
+			if err != nil {
+				return fmt.Errorf("unknown time zone %q, use a name like Europe/Stockholm", val.StringValue())
+			}
+			zoneChanged = loc.String() != user.Location().String()
+			user.TimeZone = loc.String()
+		}
+		if err = state.DB.UpdateUser(user); err != nil {
+			return err
+		}
+		// Every alert has to move to the new zone
+		if zoneChanged {
+			if err = state.RescheduleUser(user.ID); err != nil {
+				return err
+			}
+		}
+		title = "⚙️ Updated settings"
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       title,
+		Description: formatSettings(user),
+		Color:       0x57F287,
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: "Pascal • Watching your commute",
+		},
+	}
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{embed},
+		},
+	})
+}
+
+func formatSettings(user *database.User) string {
+	var sb strings.Builder
+	if user.LiveAlerts {
+		sb.WriteString("📝 **Live alerts:** on, one message per trip that counts down\n")
+	} else {
+		sb.WriteString("📝 **Live alerts:** off, every alert is a new message\n")
+	}
+	fmt.Fprintf(&sb, "🌍 **Time zone:** %s\n", user.Location())
+	fmt.Fprintf(&sb, "🔔 **Default alerts:** %s before departure\n", database.FormatAlerts(database.Trip{}.AlertOffsets(user)))
+	if user.Vacation {
+		sb.WriteString("🏝️ **Vacation mode:** on\n")
+	}
+	return sb.String()
+}
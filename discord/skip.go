@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/vincbro/pascal/database"
+	"github.com/vincbro/pascal/state"
+)
+
+func CreateSkipCommand() Command {
+	return Command{
+		Definition: &discordgo.ApplicationCommand{
+			Name:        "skip",
+			Description: "Skip a single day of a trip",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:         "name",
+					Description:  "The trip to skip",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Required:     true,
+					Autocomplete: true,
+				},
+				{
+					Name:         "date",
+					Description:  "The day to skip (YYYY-MM-DD)",
+					Type:         discordgo.ApplicationCommandOptionString,
+					Required:     true,
+					Autocomplete: true,
+				},
+				{
+					Name:        "undo",
+					Description: "Run the trip on that day after all",
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+				},
+			},
+		},
+		Handler:      skipHandler,
+		Autocomplete: skipAutocomplete,
+	}
+}
+
+func skipHandler(s *discordgo.Session, i *discordgo.InteractionCreate, state *state.State) error {
+	user, err := GetUser(i.User, i.ChannelID, state)
+	if err != nil {
+		return err
+	}
+	opts := ParseOptions(i.ApplicationCommandData().Options)
+
+	trip, err := findTrip(state, user, opts["name"].StringValue())
+	if err != nil {
+		return err
+	}
+	date, err := parseDate(opts["date"].StringValue(), userNow(state, user))
+	if err != nil {
+		return err
+	}
+	day, _ := time.ParseInLocation(database.DateLayout, date, user.Location())
+	pretty := day.Format("Monday 2 January")
+
+	var embed *discordgo.MessageEmbed
+	if val, ok := opts["undo"]; ok && val.BoolValue() {
+		if err = state.DB.RemoveSkipDate(trip.ID, date); err != nil {
+			return err
+		}
+		embed = &discordgo.MessageEmbed{
+			Title:       fmt.Sprintf("↩️ Not skipping: %s", trip.Name),
+			Description: fmt.Sprintf("The trip runs on **%s** again.", pretty),
+			Color:       0x57F287,
+		}
+		if reason, skipped := state.SkipReason(trip, day); skipped {
+			embed.Description = fmt.Sprintf("It is still skipped on **%s** (%s).", pretty, reason)
+		}
+	} else {
+		if !trip.RunsOn(day) {
+			return fmt.Errorf("%s doesn't run on %s", trip.Name, pretty)
+		}
+		if err = state.DB.AddSkipDate(&database.SkipDate{TripID: trip.ID, Date: date}); err != nil {
+			return err
+		}
+		embed = &discordgo.MessageEmbed{
+			Title:       fmt.Sprintf("⏭️ Skipping: %s", trip.Name),
+			Description: fmt.Sprintf("I won't alert you for this trip on **%s**.", pretty),
+			Color:       0x57F287,
+		}
+	}
+	embed.Footer = &discordgo.MessageEmbedFooter{
+		Text: "Pascal • Watching your commute",
+	}
+
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{embed},
+		},
+	})
+
+	return err
+}
+
+// findTrip returns the trip picked from the autocomplete, which gives its id,
+// or the trip with the name when it was typed by hand.
+func findTrip(state *state.State, user *database.User, input string) (*database.Trip, error) {
+	trip, err := state.DB.GetTrip(user.ID, input)
+	if err == nil {
+		return trip, nil
+	}
+	trips, listErr := state.DB.GetAllUsersTrips(user.ID)
+	if listErr != nil {
+		return nil, listErr
+	}
+	for _, trip := range trips {
+		if strings.EqualFold(trip.Name, strings.TrimSpace(input)) {
+			return trip, nil
+		}
+	}
+	return nil, err
+}
+
+// skipUndoCommand is the /skip command that runs the trip on date after all.
+func skipUndoCommand(trip *database.Trip, date string) string {
+	return fmt.Sprintf("/skip name:%s date:%s undo:True", trip.Name, date)
+}
+
+func skipAutocomplete(s *discordgo.Session, i *discordgo.InteractionCreate, state *state.State) error {
+	user, err := GetUser(i.User, i.ChannelID, state)
+	if err != nil {
+		return err
+	}
+	data := i.ApplicationCommandData()
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, 20)
+
+	for _, option := range data.Options {
+		if !option.Focused {
+			continue
+		}
+		switch option.Name {
+		case "name":
+			return listAutocomplete(s, i, state)
+		case "date", "until":
+			choices = append(choices, dateChoices(userNow(state, user), option.StringValue())...)
+		}
+	}
+
+	return s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{
+			Choices: choices,
+		},
+	})
+}
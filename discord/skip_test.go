@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vincbro/pascal/database"
+)
+
+func TestSkipUndoCommand(t *testing.T) {
+	s, clock, user := newTestState(t, time.Date(2026, 10, 19, 7, 0, 0, 0, time.UTC))
+	trip := &database.Trip{ID: "a1b2", UserID: user.ID, Name: "Work", Time: "08:00:00", Departure: true,
+		Recurrence: database.Recurrence{Freq: database.Daily, Interval: 1}}
+	if err := s.DB.AddTrip(trip); err != nil {
+		t.Fatalf("failed to add trip: %v", err)
+	}
+
+	command := skipUndoCommand(trip, "2026-10-19")
+	if command != "/skip name:Work date:2026-10-19 undo:True" {
+		t.Errorf("got %q", command)
+	}
+
+	// What the command fills in finds the trip and the day again
+	for _, input := range []string{"a1b2", "Work", "work "} {
+		found, err := findTrip(s, user, input)
+		if err != nil || found.ID != trip.ID {
+			t.Errorf("name %q found %+v, %v", input, found, err)
+		}
+	}
+	if _, err := findTrip(s, user, "Gym"); err == nil {
+		t.Error("found a trip that doesn't exist")
+	}
+	if date, err := parseDate("2026-10-19", clock.Now()); err != nil || date != "2026-10-19" {
+		t.Errorf("date parsed as %q, %v", date, err)
+	}
+}
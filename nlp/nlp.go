@@ -0,0 +1,462 @@
+// Package nlp turns sentences such as "Work, from: Home to: Office every day,
+// arrival 08:00:00 except saturday and sunday" into trips. It is a small rule
+// based parser and needs nothing but the sentence.
+package nlp
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/vincbro/pascal/database"
+)
+
+// Draft is what was understood from a sentence. From and To are the places as
+// they were written, see Resolve.
+type Draft struct {
+	Name      string
+	From      string
+	To        string
+	Departure bool
+	// Time is formatted HH:MM:SS
+	Time       string
+	Recurrence database.Recurrence
+	// Date is set instead of Recurrence for trips that only run once
+	Date string
+}
+
+// ParseError is returned when a required part of the trip is missing or
+// can't be read. Pos is the byte offset in the input where the part was
+// expected, or -1 if nothing in the input points at it.
+type ParseError struct {
+	Missing string
+	Hint    string
+	Pos     int
+}
+
+func (e *ParseError) Error() string {
+	if e.Pos >= 0 {
+		return fmt.Sprintf("no %s found at %d, %s", e.Missing, e.Pos, e.Hint)
+	}
+	return fmt.Sprintf("no %s found, %s", e.Missing, e.Hint)
+}
+
+type token struct {
+	raw  string
+	word string
+	// pos is the byte offset of the token in the input
+	pos int
+	// quoted tokens are always part of a place
+	quoted bool
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+	"sunday": time.Sunday, "sun": time.Sunday,
+}
+
+var (
+	weekdays    = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+	weekends    = []time.Weekday{time.Saturday, time.Sunday}
+	everyDay    = append(slices.Clone(weekdays), weekends...)
+	ordinalDays = map[string]int{"first": 1, "1st": 1, "second": 2, "2nd": 2, "third": 3, "3rd": 3, "fourth": 4, "4th": 4, "last": -1}
+)
+
+var arriveWords = []string{"arrive", "arrival", "arriving", "arrives", "by"}
+var departWords = []string{"depart", "departure", "departing", "departs", "leave", "leaving", "leaves"}
+
+// stopWords end a place.
+var stopWords = []string{
+	"from", "to", "at", "every", "each", "daily", "everyday", "on", "except", "excluding", "but",
+	"until", "today", "tomorrow", "next", "weekday", "weekdays", "weekend", "weekends",
+}
+
+// weekday returns the day a word such as "mon" or "fridays" names.
+func weekday(word string) (time.Weekday, bool) {
+	if day, ok := weekdayNames[word]; ok {
+		return day, true
+	}
+	day, ok := weekdayNames[strings.TrimSuffix(word, "s")]
+	return day, ok
+}
+
+func isStop(word string) bool {
+	if slices.Contains(stopWords, word) || slices.Contains(arriveWords, word) || slices.Contains(departWords, word) {
+		return true
+	}
+	_, ok := weekday(word)
+	return ok
+}
+
+func tokenize(input string) []token {
+	runes := []rune(input)
+	// offsets[i] is the byte offset of runes[i]
+	offsets := make([]int, len(runes)+1)
+	for i, r := range runes {
+		offsets[i+1] = offsets[i] + utf8.RuneLen(r)
+	}
+
+	tokens := make([]token, 0)
+	var current strings.Builder
+	start := 0
+	flush := func() {
+		if current.Len() == 0 {
+			return
+		}
+		raw := current.String()
+		current.Reset()
+		word := strings.TrimRight(strings.ToLower(raw), ":.!?")
+		tokens = append(tokens, token{raw: strings.TrimRight(raw, ":!?"), word: word, pos: start})
+	}
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '"' || r == '“' || r == '”':
+			flush()
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' && runes[end] != '”' {
+				end++
+			}
+			text := strings.TrimSpace(string(runes[i+1 : min(end, len(runes))]))
+			tokens = append(tokens, token{raw: text, word: strings.ToLower(text), pos: offsets[i+1], quoted: true})
+			i = end
+		case r == ',' || r == ';':
+			flush()
+			tokens = append(tokens, token{raw: ",", word: ",", pos: offsets[i]})
+		case r == ' ' || r == '\t' || r == '\n':
+			flush()
+		default:
+			if current.Len() == 0 {
+				start = offsets[i]
+			}
+			current.WriteRune(r)
+		}
+	}
+	flush()
+	return tokens
+}
+
+var clockPattern = regexp.MustCompile(`^(\d{1,2})(?:[:.](\d{2}))?(?:[:.](\d{2}))?(am|pm)?$`)
+
+// parseTime reads the time starting at tokens[i], returning it as HH:MM:SS
+// and how many tokens it used. A bare hour is only accepted when prefixed by
+// something that makes it a time, e.g. "at 8".
+func parseTime(tokens []token, i int) (string, int, bool) {
+	word := tokens[i].word
+	if tokens[i].quoted {
+		return "", 0, false
+	}
+	switch word {
+	case "noon", "midday":
+		return "12:00:00", 1, true
+	case "midnight":
+		return "00:00:00", 1, true
+	}
+
+	used := 1
+	if i+1 < len(tokens) && (tokens[i+1].word == "am" || tokens[i+1].word == "pm") {
+		word += tokens[i+1].word
+		used = 2
+	}
+	match := clockPattern.FindStringSubmatch(word)
+	if match == nil {
+		return "", 0, false
+	}
+	hasMinutes := match[2] != ""
+	meridiem := match[4]
+	if !hasMinutes && meridiem == "" {
+		if i == 0 || !slices.Contains(append([]string{"at"}, append(arriveWords, departWords...)...), tokens[i-1].word) {
+			return "", 0, false
+		}
+	}
+
+	hour, _ := strconv.Atoi(match[1])
+	minute, second := 0, 0
+	if hasMinutes {
+		minute, _ = strconv.Atoi(match[2])
+	}
+	if match[3] != "" {
+		second, _ = strconv.Atoi(match[3])
+	}
+	switch meridiem {
+	case "am", "pm":
+		if hour < 1 || hour > 12 {
+			return "", 0, false
+		}
+		hour %= 12
+		if meridiem == "pm" {
+			hour += 12
+		}
+	}
+	if hour > 23 || minute > 59 || second > 59 {
+		return "", 0, false
+	}
+	return fmt.Sprintf("%02d:%02d:%02d", hour, minute, second), used, true
+}
+
+// looksLikeTime reports whether tokens[i] is written like a time, e.g. "25:00"
+// or "13pm", even if it isn't a valid one.
+func looksLikeTime(tokens []token, i int) bool {
+	if tokens[i].quoted {
+		return false
+	}
+	word := tokens[i].word
+	if i+1 < len(tokens) && (tokens[i+1].word == "am" || tokens[i+1].word == "pm") {
+		word += tokens[i+1].word
+	}
+	match := clockPattern.FindStringSubmatch(word)
+	return match != nil && (match[2] != "" || match[4] != "")
+}
+
+func join(tokens []token) string {
+	words := make([]string, len(tokens))
+	for i, t := range tokens {
+		words[i] = t.raw
+	}
+	return strings.Join(words, " ")
+}
+
+// expectedAt is the byte offset of tokens[i], or the end of input if the
+// tokens ran out.
+func expectedAt(input string, tokens []token, i int) int {
+	if i < len(tokens) {
+		return tokens[i].pos
+	}
+	return len(input)
+}
+
+func parseDate(word string) (string, bool) {
+	date, err := time.Parse(database.DateLayout, word)
+	if err != nil {
+		return "", false
+	}
+	return date.Format(database.DateLayout), true
+}
+
+// Parse reads a trip out of input, relative dates such as "tomorrow" are
+// relative to now.
+func Parse(input string, now time.Time) (Draft, error) {
+	tokens := tokenize(input)
+	draft := Draft{Departure: true}
+	// fromPos and toPos are where the places were expected, if asked for
+	fromPos, toPos := -1, -1
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	// A leading part without any keywords, ended by a comma, is the name
+	if end := slices.IndexFunc(tokens, func(t token) bool { return t.word == "," }); end > 0 {
+		named := true
+		for i, t := range tokens[:end] {
+			if _, _, ok := parseTime(tokens, i); ok || t.quoted || isStop(t.word) {
+				named = false
+				break
+			}
+		}
+		if named {
+			draft.Name = join(tokens[:end])
+			tokens = tokens[end+1:]
+		}
+	}
+
+	// Otherwise the words before the first keyword are, e.g. "Gym from ..."
+	if draft.Name == "" {
+		end := 0
+		for ; end < len(tokens); end++ {
+			if _, _, ok := parseTime(tokens, end); ok || tokens[end].quoted || tokens[end].word == "," || isStop(tokens[end].word) {
+				break
+			}
+		}
+		if end > 0 && end < len(tokens) {
+			draft.Name = join(tokens[:end])
+			tokens = tokens[end:]
+		}
+	}
+
+	// place joins the tokens from i up to the next stop word or comma
+	place := func(i int) (string, int) {
+		parts := make([]string, 0)
+		for ; i < len(tokens); i++ {
+			t := tokens[i]
+			if t.quoted {
+				parts = append(parts, t.raw)
+				continue
+			}
+			if t.word == "," || isStop(t.word) {
+				break
+			}
+			if looksLikeTime(tokens, i) {
+				break
+			}
+			if _, _, ok := parseTime(tokens, i); ok {
+				break
+			}
+			parts = append(parts, t.raw)
+		}
+		return strings.Join(parts, " "), i
+	}
+
+	included := make([]time.Weekday, 0, 7)
+	excluded := make([]time.Weekday, 0, 7)
+	monthly := make([]database.RecurDay, 0)
+	interval := 1
+	daily := false
+	except := false
+	addDays := func(days ...time.Weekday) {
+		if except {
+			excluded = append(excluded, days...)
+		} else {
+			included = append(included, days...)
+		}
+	}
+
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		if t.quoted {
+			continue
+		}
+		word := t.word
+		next := ""
+		if i+1 < len(tokens) {
+			next = tokens[i+1].word
+		}
+
+		// An exception lasts for the days listed right after it
+		if _, isDay := weekday(word); !isDay && !slices.Contains([]string{"and", "or", "on", "the", "weekend", "weekends", "weekday", "weekdays", "except", "excluding", "but", "not"}, word) {
+			except = false
+		}
+
+		if hms, used, ok := parseTime(tokens, i); ok {
+			draft.Time = hms
+			i += used - 1
+			continue
+		}
+		if looksLikeTime(tokens, i) {
+			return Draft{}, &ParseError{
+				Missing: "time",
+				Hint:    fmt.Sprintf(`"%s" is not a time of day, use e.g. "08:00" or "7:30am"`, t.raw),
+				Pos:     t.pos,
+			}
+		}
+		if date, ok := parseDate(word); ok {
+			if i > 0 && tokens[i-1].word == "until" {
+				draft.Recurrence.Until = date
+			} else {
+				draft.Date = date
+			}
+			continue
+		}
+		if day, ok := weekday(word); ok {
+			addDays(day)
+			continue
+		}
+
+		switch {
+		case word == "from":
+			fromPos = expectedAt(input, tokens, i+1)
+			draft.From, i = place(i + 1)
+			i--
+		case word == "to":
+			toPos = expectedAt(input, tokens, i+1)
+			draft.To, i = place(i + 1)
+			i--
+		case slices.Contains(arriveWords, word):
+			draft.Departure = false
+		case slices.Contains(departWords, word):
+			draft.Departure = true
+		case word == "except" || word == "excluding" || word == "not":
+			except = true
+		case word == "daily" || word == "everyday":
+			daily = true
+		case word == "weekday" || word == "weekdays":
+			addDays(weekdays...)
+		case word == "weekend" || word == "weekends":
+			addDays(weekends...)
+		case word == "today":
+			draft.Date = today.Format(database.DateLayout)
+		case word == "tomorrow":
+			draft.Date = today.AddDate(0, 0, 1).Format(database.DateLayout)
+		case word == "next":
+			if day, ok := weekday(next); ok {
+				ahead := (int(day)-int(today.Weekday())+6)%7 + 1
+				draft.Date = today.AddDate(0, 0, ahead).Format(database.DateLayout)
+				i++
+			}
+		case word == "every" || word == "each":
+			switch {
+			case next == "other" || next == "second":
+				interval = 2
+				i++
+			default:
+				if n, err := strconv.Atoi(next); err == nil && n > 1 {
+					interval = n
+					i++
+				}
+			}
+			if i+1 < len(tokens) && strings.TrimSuffix(tokens[i+1].word, "s") == "day" {
+				daily = true
+				i++
+			}
+		default:
+			if n, ok := ordinalDays[word]; ok {
+				if day, isDay := weekday(next); isDay {
+					monthly = append(monthly, database.RecurDay{N: n, Weekday: day})
+					i++
+				}
+			}
+		}
+	}
+
+	if draft.From == "" {
+		return Draft{}, &ParseError{Missing: "starting point", Hint: `say where you leave from, e.g. "from Home"`, Pos: fromPos}
+	}
+	if draft.To == "" {
+		return Draft{}, &ParseError{Missing: "destination", Hint: `say where you are going, e.g. "to Office"`, Pos: toPos}
+	}
+	if draft.Time == "" {
+		return Draft{}, &ParseError{Missing: "time", Hint: `say when, e.g. "arrive by 08:00" or "at 7:30am"`, Pos: -1}
+	}
+	if draft.Name == "" {
+		draft.Name = fmt.Sprintf("%s ➔ %s", draft.From, draft.To)
+	}
+
+	if draft.Date != "" {
+		draft.Recurrence = database.Recurrence{}
+		return draft, nil
+	}
+
+	until := draft.Recurrence.Until
+	switch {
+	case len(monthly) > 0:
+		draft.Recurrence = database.Recurrence{Freq: database.Monthly, Interval: interval, ByDay: monthly}
+	case daily && len(included) == 0 && len(excluded) == 0:
+		draft.Recurrence = database.Recurrence{Freq: database.Daily, Interval: interval}
+	default:
+		if len(included) == 0 {
+			included = everyDay
+		}
+		days := make([]time.Weekday, 0, len(included))
+		for _, day := range included {
+			if !slices.Contains(excluded, day) {
+				days = append(days, day)
+			}
+		}
+		draft.Recurrence = database.WeeklyOn(days...)
+		if !draft.Recurrence.IsZero() {
+			draft.Recurrence.Interval = interval
+		}
+	}
+	if !draft.Recurrence.IsZero() {
+		draft.Recurrence.Until = until
+		draft.Recurrence = draft.Recurrence.StartingOn(today.Format(database.DateLayout))
+	}
+	return draft, nil
+}
@@ -0,0 +1,242 @@
+package nlp
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// now is a Monday
+var now = time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input     string
+		name      string
+		from, to  string
+		departure bool
+		time      string
+		// rule is the RRULE of the recurrence, empty for one-off trips
+		rule  string
+		date  string
+		until string
+	}{
+		// Names and places
+		{
+			input: "Work, from: Home to: Office every day, arrival 08:00:00 except saturday and sunday",
+			name:  "Work", from: "Home", to: "Office", time: "08:00:00",
+			rule: "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR",
+		},
+		{
+			input: "Gym from Odenplan to Tekniska högskolan at 18:30 on tuesdays",
+			name:  "Gym", from: "Odenplan", to: "Tekniska högskolan", departure: true, time: "18:30:00",
+			rule: "FREQ=WEEKLY;BYDAY=TU",
+		},
+		{
+			input: "from Odenplan to Solna centrum at 7:15",
+			name:  "Odenplan ➔ Solna centrum", from: "Odenplan", to: "Solna centrum", departure: true, time: "07:15:00",
+			rule: "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR,SA,SU",
+		},
+		{
+			input: `Dinner from "T-Centralen" to "Stop at the Mall" at 19:00 daily`,
+			name:  "Dinner", from: "T-Centralen", to: "Stop at the Mall", departure: true, time: "19:00:00",
+			rule: "FREQ=DAILY",
+		},
+		{
+			input: `from “Home, sweet home” to Work at 8am daily`,
+			name:  "Home, sweet home ➔ Work", from: "Home, sweet home", to: "Work", departure: true, time: "08:00:00",
+			rule: "FREQ=DAILY",
+		},
+
+		// 12h and 24h times
+		{
+			input: "from Home to Work at 7:30am weekdays",
+			name:  "Home ➔ Work", from: "Home", to: "Work", departure: true, time: "07:30:00",
+			rule: "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR",
+		},
+		{
+			input: "from Home to Work arrive by 5 pm weekdays",
+			name:  "Home ➔ Work", from: "Home", to: "Work", time: "17:00:00",
+			rule: "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR",
+		},
+		{
+			input: "from Home to Work at 12am daily",
+			name:  "Home ➔ Work", from: "Home", to: "Work", departure: true, time: "00:00:00",
+			rule: "FREQ=DAILY",
+		},
+		{
+			input: "from Home to Work at 12pm daily",
+			name:  "Home ➔ Work", from: "Home", to: "Work", departure: true, time: "12:00:00",
+			rule: "FREQ=DAILY",
+		},
+		{
+			input: "from Home to Work leave 8 daily",
+			name:  "Home ➔ Work", from: "Home", to: "Work", departure: true, time: "08:00:00",
+			rule: "FREQ=DAILY",
+		},
+		{
+			input: "from Home to Work at 23.45 daily",
+			name:  "Home ➔ Work", from: "Home", to: "Work", departure: true, time: "23:45:00",
+			rule: "FREQ=DAILY",
+		},
+		{
+			input: "from Home to Work at noon on sundays",
+			name:  "Home ➔ Work", from: "Home", to: "Work", departure: true, time: "12:00:00",
+			rule: "FREQ=WEEKLY;BYDAY=SU",
+		},
+
+		// Weekday lists and exceptions
+		{
+			input: "from Home to Work at 08:00 mon, wed and fri",
+			name:  "Home ➔ Work", from: "Home", to: "Work", departure: true, time: "08:00:00",
+			rule: "FREQ=WEEKLY;BYDAY=MO,WE,FR",
+		},
+		{
+			input: "from Home to Work at 08:00 every day except wednesday",
+			name:  "Home ➔ Work", from: "Home", to: "Work", departure: true, time: "08:00:00",
+			rule: "FREQ=WEEKLY;BYDAY=MO,TU,TH,FR,SA,SU",
+		},
+		{
+			input: "from Home to Work at 08:00 weekdays but not fridays",
+			name:  "Home ➔ Work", from: "Home", to: "Work", departure: true, time: "08:00:00",
+			rule: "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH",
+		},
+		{
+			input: "from Home to Work at 10:00 weekends",
+			name:  "Home ➔ Work", from: "Home", to: "Work", departure: true, time: "10:00:00",
+			rule: "FREQ=WEEKLY;BYDAY=SA,SU",
+		},
+
+		// Intervals
+		{
+			input: "from Home to Work at 08:00 every other friday",
+			name:  "Home ➔ Work", from: "Home", to: "Work", departure: true, time: "08:00:00",
+			rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=FR",
+		},
+		{
+			input: "from Home to Work at 08:00 every 3 days",
+			name:  "Home ➔ Work", from: "Home", to: "Work", departure: true, time: "08:00:00",
+			rule: "FREQ=DAILY;INTERVAL=3",
+		},
+		{
+			input: "from Home to Work at 08:00 every second day",
+			name:  "Home ➔ Work", from: "Home", to: "Work", departure: true, time: "08:00:00",
+			rule: "FREQ=DAILY;INTERVAL=2",
+		},
+
+		// Monthly
+		{
+			input: "Book club from Home to Library at 18:00 first monday",
+			name:  "Book club", from: "Home", to: "Library", departure: true, time: "18:00:00",
+			rule: "FREQ=MONTHLY;BYDAY=1MO",
+		},
+		{
+			input: "from Home to Library at 18:00 every last friday",
+			name:  "Home ➔ Library", from: "Home", to: "Library", departure: true, time: "18:00:00",
+			rule: "FREQ=MONTHLY;BYDAY=-1FR",
+		},
+
+		// One-off trips
+		{
+			input: "from Home to Airport at 05:00 tomorrow",
+			name:  "Home ➔ Airport", from: "Home", to: "Airport", departure: true, time: "05:00:00",
+			date: "2026-10-20",
+		},
+		{
+			input: "from Home to Airport at 05:00 today",
+			name:  "Home ➔ Airport", from: "Home", to: "Airport", departure: true, time: "05:00:00",
+			date: "2026-10-19",
+		},
+		{
+			input: "from Home to Airport at 05:00 next friday",
+			name:  "Home ➔ Airport", from: "Home", to: "Airport", departure: true, time: "05:00:00",
+			date: "2026-10-23",
+		},
+		{
+			// Next monday on a monday is a week away
+			input: "from Home to Airport at 05:00 next monday",
+			name:  "Home ➔ Airport", from: "Home", to: "Airport", departure: true, time: "05:00:00",
+			date: "2026-10-26",
+		},
+		{
+			input: "from Home to Airport at 05:00 2026-12-24",
+			name:  "Home ➔ Airport", from: "Home", to: "Airport", departure: true, time: "05:00:00",
+			date: "2026-12-24",
+		},
+
+		// Until
+		{
+			input: "from Home to School at 07:45 weekdays until 2026-12-18",
+			name:  "Home ➔ School", from: "Home", to: "School", departure: true, time: "07:45:00",
+			rule: "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR;UNTIL=20261218", until: "2026-12-18",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			draft, err := Parse(tt.input, now)
+			if err != nil {
+				t.Fatalf("failed to parse: %v", err)
+			}
+			if draft.Name != tt.name {
+				t.Errorf("name %q, want %q", draft.Name, tt.name)
+			}
+			if draft.From != tt.from || draft.To != tt.to {
+				t.Errorf("%q ➔ %q, want %q ➔ %q", draft.From, draft.To, tt.from, tt.to)
+			}
+			if draft.Departure != tt.departure {
+				t.Errorf("departure %v, want %v", draft.Departure, tt.departure)
+			}
+			if draft.Time != tt.time {
+				t.Errorf("time %q, want %q", draft.Time, tt.time)
+			}
+			if rule := draft.Recurrence.Rule(); rule != tt.rule {
+				t.Errorf("rule %q, want %q", rule, tt.rule)
+			}
+			if draft.Date != tt.date {
+				t.Errorf("date %q, want %q", draft.Date, tt.date)
+			}
+			if draft.Recurrence.Until != tt.until {
+				t.Errorf("until %q, want %q", draft.Recurrence.Until, tt.until)
+			}
+			if tt.rule != "" && draft.Recurrence.Start != "2026-10-19" {
+				t.Errorf("recurrence starts %q, want today", draft.Recurrence.Start)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input   string
+		missing string
+		pos     int
+	}{
+		{"to Work at 08:00", "starting point", -1},
+		{"from to Work at 08:00", "starting point", 5},
+		{"from Home at 08:00", "destination", -1},
+		{"from Home to", "destination", 12},
+		{"from Home to, at 08:00", "destination", 12},
+		{"from Home to Work", "time", -1},
+		{"from Home to Work at 25:00", "time", 21},
+		{"from Home to Work at 13pm", "time", 21},
+		{"from Home to Work at 8:75", "time", 21},
+		// Offsets are in bytes of the input
+		{"from Hötorget to Work at 24:10", "time", 26},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := Parse(tt.input, now)
+			var parseErr *ParseError
+			if !errors.As(err, &parseErr) {
+				t.Fatalf("got error %v, want a ParseError", err)
+			}
+			if parseErr.Missing != tt.missing || parseErr.Pos != tt.pos {
+				t.Errorf("missing %s at %d, want %s at %d", parseErr.Missing, parseErr.Pos, tt.missing, tt.pos)
+			}
+			if parseErr.Hint == "" {
+				t.Error("error has no hint")
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package nlp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/vincbro/pascal/blaise"
+	"github.com/vincbro/pascal/database"
+)
+
+// Searcher finds locations by name, *blaise.Client is one.
+type Searcher interface {
+	SearchAreas(ctx context.Context, query string, count int) ([]blaise.Location, error)
+}
+
+// Resolve looks up a place the way it was written. The users saved places
+// are matched by alias first, then the locations in known, usually the users
+// history, by name before searching.
+func Resolve(ctx context.Context, searcher Searcher, place string, places []*database.Place, known []blaise.Location) (blaise.Location, error) {
+	for _, saved := range places {
+		if strings.EqualFold(saved.Alias, place) {
+			return saved.Location, nil
+		}
+	}
+	for _, loc := range known {
+		if strings.EqualFold(loc.Name, place) {
+			return loc, nil
+		}
+	}
+	results, err := searcher.SearchAreas(ctx, place, 1)
+	if err != nil {
+		return blaise.Location{}, err
+	}
+	if len(results) == 0 {
+		return blaise.Location{}, fmt.Errorf("%w: %q", blaise.ErrUnknownLocation, place)
+	}
+	return results[0], nil
+}
+
+// Trip turns the draft into a trip of the user with its places resolved, the
+// itinerary is left for the caller to route.
+func (d Draft) Trip(ctx context.Context, searcher Searcher, user *database.User, places []*database.Place) (database.Trip, error) {
+	from, err := Resolve(ctx, searcher, d.From, places, user.Locations)
+	if err != nil {
+		return database.Trip{}, err
+	}
+	to, err := Resolve(ctx, searcher, d.To, places, user.Locations)
+	if err != nil {
+		return database.Trip{}, err
+	}
+	return database.Trip{
+		UserID:     user.ID,
+		Name:       d.Name,
+		FromID:     from.ID,
+		From:       from.Name,
+		ToID:       to.ID,
+		To:         to.Name,
+		Time:       d.Time,
+		Departure:  d.Departure,
+		Recurrence: d.Recurrence,
+		Date:       d.Date,
+	}, nil
+}
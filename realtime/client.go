@@ -0,0 +1,104 @@
+package realtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StaleAfter is how long a feed is trusted after it was last fetched, older
+// predictions are worse than none.
+const StaleAfter = 5 * time.Minute
+
+// Client polls a set of GTFS-RT feeds, each either an http(s) URL or a path
+// to a file that is kept up to date by something else. Trip updates and
+// service alerts are often published as separate feeds.
+type Client struct {
+	Sources    []string
+	HTTPClient *http.Client
+
+	mu    sync.RWMutex
+	feeds map[string]fetched
+}
+
+type fetched struct {
+	feed *Feed
+	at   time.Time
+}
+
+func NewClient(sources ...string) *Client {
+	return &Client{
+		Sources:    sources,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+		feeds:      make(map[string]fetched),
+	}
+}
+
+// Refresh fetches every source. A source that fails keeps its last feed until
+// it goes stale, the errors of all failing sources are joined.
+func (c *Client) Refresh(ctx context.Context, now time.Time) error {
+	var errs []error
+	for _, source := range c.Sources {
+		data, err := c.read(ctx, source)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to read %s: %w", source, err))
+			continue
+		}
+		feed, err := Parse(data)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to parse %s: %w", source, err))
+			continue
+		}
+		c.mu.Lock()
+		c.feeds[source] = fetched{feed: feed, at: now}
+		c.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Client) read(ctx context.Context, source string) ([]byte, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return os.ReadFile(source)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// Feed merges the feeds that aren't stale at now. It is nil if there are none.
+func (c *Client) Feed(now time.Time) *Feed {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var merged *Feed
+	for _, source := range c.Sources {
+		f, ok := c.feeds[source]
+		if !ok || now.Sub(f.at) > StaleAfter {
+			continue
+		}
+		if merged == nil {
+			merged = &Feed{Timestamp: f.feed.Timestamp}
+		}
+		merged.TripUpdates = append(merged.TripUpdates, f.feed.TripUpdates...)
+		merged.Alerts = append(merged.Alerts, f.feed.Alerts...)
+		if f.feed.Timestamp.Before(merged.Timestamp) {
+			merged.Timestamp = f.feed.Timestamp
+		}
+	}
+	return merged
+}
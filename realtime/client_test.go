@@ -0,0 +1,82 @@
+package realtime
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRefresh(t *testing.T) {
+	var alertsDown atomic.Bool
+	mux := http.NewServeMux()
+	mux.HandleFunc("/trip-updates", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(readFixture(t, "trip_updates.pb"))
+	})
+	mux.HandleFunc("/alerts", func(w http.ResponseWriter, r *http.Request) {
+		if alertsDown.Load() {
+			http.Error(w, "down", http.StatusBadGateway)
+			return
+		}
+		w.Write(readFixture(t, "alerts.pb"))
+	})
+	mux.HandleFunc("/garbage", func(w http.ResponseWriter, r *http.Request) {
+		data := readFixture(t, "alerts.pb")
+		w.Write(data[:len(data)-3])
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	client := NewClient(server.URL+"/trip-updates", server.URL+"/alerts")
+	now := feedTime.Add(time.Minute)
+	if client.Feed(now) != nil {
+		t.Error("got a feed before the first refresh")
+	}
+	if err := client.Refresh(context.Background(), now); err != nil {
+		t.Fatalf("refresh failed: %v", err)
+	}
+	feed := client.Feed(now)
+	if feed == nil || len(feed.TripUpdates) != 5 || len(feed.Alerts) != 3 {
+		t.Fatalf("merged feed is %+v, want both sources", feed)
+	}
+
+	// A failing source keeps its last feed until it is stale
+	alertsDown.Store(true)
+	later := now.Add(StaleAfter - time.Minute)
+	err := client.Refresh(context.Background(), later)
+	if err == nil || !strings.Contains(err.Error(), "/alerts") {
+		t.Errorf("got error %v, want the failing source", err)
+	}
+	if feed := client.Feed(later.Add(time.Minute)); feed == nil || len(feed.Alerts) != 3 {
+		t.Error("lost the alerts of a source that only just failed")
+	}
+	if feed := client.Feed(now.Add(StaleAfter + time.Second)); feed == nil || len(feed.Alerts) != 0 || len(feed.TripUpdates) != 5 {
+		t.Errorf("stale alerts are still served: %+v", feed)
+	}
+	if client.Feed(later.Add(StaleAfter+time.Second)) != nil {
+		t.Error("got a feed after every source went stale")
+	}
+
+	broken := NewClient(server.URL+"/garbage", server.URL+"/missing")
+	err = broken.Refresh(context.Background(), now)
+	if err == nil || !strings.Contains(err.Error(), "failed to parse") || !strings.Contains(err.Error(), "404") {
+		t.Errorf("got error %v, want a parse error and a missing feed", err)
+	}
+	if broken.Feed(now) != nil {
+		t.Error("got a feed from sources that never worked")
+	}
+}
+
+func TestRefreshFile(t *testing.T) {
+	client := NewClient(filepath.Join("testdata", "alerts.pb"), filepath.Join("testdata", "missing.pb"))
+	if err := client.Refresh(context.Background(), feedTime); err == nil {
+		t.Error("a missing file was ignored")
+	}
+	if feed := client.Feed(feedTime); feed == nil || len(feed.Alerts) != 3 {
+		t.Errorf("got feed %+v from file, want its alerts", feed)
+	}
+}
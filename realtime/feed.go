@@ -0,0 +1,494 @@
+// Package realtime reads GTFS-Realtime trip updates and service alerts and
+// matches them to the legs of planned itineraries.
+package realtime
+
+import (
+	"fmt"
+	"time"
+)
+
+// Effect is what a service alert does to the service, the values are those of
+// the GTFS-RT Alert.Effect enum.
+type Effect int
+
+const (
+	EffectNoService         Effect = 1
+	EffectReducedService    Effect = 2
+	EffectSignificantDelays Effect = 3
+	EffectDetour            Effect = 4
+	EffectAdditionalService Effect = 5
+	EffectModifiedService   Effect = 6
+	EffectOther             Effect = 7
+	EffectUnknown           Effect = 8
+	EffectStopMoved         Effect = 9
+	EffectNone              Effect = 10
+)
+
+// Feed is the part of a GTFS-RT FeedMessage pascal cares about, vehicle
+// positions are dropped.
+type Feed struct {
+	Timestamp   time.Time
+	TripUpdates []TripUpdate
+	Alerts      []Alert
+}
+
+// TripUpdate is the prediction for a single trip.
+type TripUpdate struct {
+	TripID  string
+	RouteID string
+	// StartDate is the service date of the trip as YYYYMMDD, it may be empty
+	StartDate string
+	Canceled  bool
+	// Delay applies to every stop without a stop time update, if HasDelay
+	Delay     time.Duration
+	HasDelay  bool
+	StopTimes []StopTimeUpdate
+}
+
+// StopTimeUpdate is the prediction for a stop of a trip. The delay carries on
+// to the following stops unless they have their own update.
+type StopTimeUpdate struct {
+	StopSequence   uint32
+	StopID         string
+	ArrivalDelay   time.Duration
+	DepartureDelay time.Duration
+	HasDelay       bool
+	Skipped        bool
+}
+
+// Delay is the departure delay of the stop, or the arrival delay if there is
+// none.
+func (u StopTimeUpdate) Delay() time.Duration {
+	if u.DepartureDelay != 0 {
+		return u.DepartureDelay
+	}
+	return u.ArrivalDelay
+}
+
+// Alert is a service alert, active during any of its periods or always if it
+// has none.
+type Alert struct {
+	ID          string
+	Effect      Effect
+	Header      string
+	Description string
+	Periods     []Period
+	Informed    []Entity
+}
+
+// Period is when an alert is active, a zero Start or End is open.
+type Period struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Entity is a route, trip or stop an alert is about. Every field that is set
+// has to match.
+type Entity struct {
+	RouteID string
+	TripID  string
+	StopID  string
+}
+
+// Active reports whether the alert is active at t.
+func (a Alert) Active(t time.Time) bool {
+	if len(a.Periods) == 0 {
+		return true
+	}
+	for _, period := range a.Periods {
+		if (period.Start.IsZero() || !t.Before(period.Start)) && (period.End.IsZero() || t.Before(period.End)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse decodes a GTFS-RT FeedMessage.
+func Parse(data []byte) (*Feed, error) {
+	feed := &Feed{}
+	d := &decoder{buf: data}
+	for !d.done() {
+		num, wire, err := d.field()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case num == 1 && wire == wireLen:
+			header, err := d.message()
+			if err != nil {
+				return nil, err
+			}
+			if feed.Timestamp, err = parseHeader(header); err != nil {
+				return nil, fmt.Errorf("invalid feed header: %w", err)
+			}
+		case num == 2 && wire == wireLen:
+			entity, err := d.message()
+			if err != nil {
+				return nil, err
+			}
+			if err = feed.parseEntity(entity); err != nil {
+				return nil, fmt.Errorf("invalid feed entity: %w", err)
+			}
+		default:
+			if err := d.skip(wire); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return feed, nil
+}
+
+func parseHeader(d *decoder) (time.Time, error) {
+	var timestamp time.Time
+	for !d.done() {
+		num, wire, err := d.field()
+		if err != nil {
+			return timestamp, err
+		}
+		if num == 3 && wire == wireVarint {
+			seconds, err := d.varint()
+			if err != nil {
+				return timestamp, err
+			}
+			timestamp = time.Unix(int64(seconds), 0)
+			continue
+		}
+		if err := d.skip(wire); err != nil {
+			return timestamp, err
+		}
+	}
+	return timestamp, nil
+}
+
+func (f *Feed) parseEntity(d *decoder) error {
+	var id string
+	var deleted bool
+	var update *TripUpdate
+	var alert *Alert
+	for !d.done() {
+		num, wire, err := d.field()
+		if err != nil {
+			return err
+		}
+		switch {
+		case num == 1 && wire == wireLen:
+			id, err = d.string()
+		case num == 2 && wire == wireVarint:
+			var v uint64
+			v, err = d.varint()
+			deleted = v != 0
+		case num == 3 && wire == wireLen:
+			var m *decoder
+			if m, err = d.message(); err == nil {
+				var parsed TripUpdate
+				parsed, err = parseTripUpdate(m)
+				update = &parsed
+			}
+		case num == 5 && wire == wireLen:
+			var m *decoder
+			if m, err = d.message(); err == nil {
+				var parsed Alert
+				parsed, err = parseAlert(m)
+				alert = &parsed
+			}
+		default:
+			err = d.skip(wire)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if deleted {
+		return nil
+	}
+	if update != nil {
+		f.TripUpdates = append(f.TripUpdates, *update)
+	}
+	if alert != nil {
+		alert.ID = id
+		f.Alerts = append(f.Alerts, *alert)
+	}
+	return nil
+}
+
+// tripDescriptor is the GTFS-RT TripDescriptor.
+type tripDescriptor struct {
+	tripID    string
+	routeID   string
+	startDate string
+	canceled  bool
+}
+
+func parseTripDescriptor(d *decoder) (tripDescriptor, error) {
+	var trip tripDescriptor
+	for !d.done() {
+		num, wire, err := d.field()
+		if err != nil {
+			return trip, err
+		}
+		switch {
+		case num == 1 && wire == wireLen:
+			trip.tripID, err = d.string()
+		case num == 3 && wire == wireLen:
+			trip.startDate, err = d.string()
+		case num == 4 && wire == wireVarint:
+			var v uint64
+			v, err = d.varint()
+			// CANCELED, and DELETED which is a cancellation riders shouldn't see
+			trip.canceled = v == 3 || v == 7
+		case num == 5 && wire == wireLen:
+			trip.routeID, err = d.string()
+		default:
+			err = d.skip(wire)
+		}
+		if err != nil {
+			return trip, err
+		}
+	}
+	return trip, nil
+}
+
+func parseTripUpdate(d *decoder) (TripUpdate, error) {
+	var update TripUpdate
+	for !d.done() {
+		num, wire, err := d.field()
+		if err != nil {
+			return update, err
+		}
+		switch {
+		case num == 1 && wire == wireLen:
+			var m *decoder
+			if m, err = d.message(); err == nil {
+				var trip tripDescriptor
+				trip, err = parseTripDescriptor(m)
+				update.TripID, update.RouteID, update.StartDate, update.Canceled = trip.tripID, trip.routeID, trip.startDate, trip.canceled
+			}
+		case num == 2 && wire == wireLen:
+			var m *decoder
+			if m, err = d.message(); err == nil {
+				var stop StopTimeUpdate
+				stop, err = parseStopTimeUpdate(m)
+				update.StopTimes = append(update.StopTimes, stop)
+			}
+		case num == 5 && wire == wireVarint:
+			var delay int32
+			delay, err = d.int32()
+			update.Delay, update.HasDelay = time.Duration(delay)*time.Second, true
+		default:
+			err = d.skip(wire)
+		}
+		if err != nil {
+			return update, err
+		}
+	}
+	return update, nil
+}
+
+func parseStopTimeUpdate(d *decoder) (StopTimeUpdate, error) {
+	var stop StopTimeUpdate
+	for !d.done() {
+		num, wire, err := d.field()
+		if err != nil {
+			return stop, err
+		}
+		switch {
+		case num == 1 && wire == wireVarint:
+			var v uint64
+			v, err = d.varint()
+			stop.StopSequence = uint32(v)
+		case (num == 2 || num == 3) && wire == wireLen:
+			var m *decoder
+			if m, err = d.message(); err == nil {
+				var delay time.Duration
+				var ok bool
+				delay, ok, err = parseStopTimeEvent(m)
+				if num == 2 {
+					stop.ArrivalDelay = delay
+				} else {
+					stop.DepartureDelay = delay
+				}
+				stop.HasDelay = stop.HasDelay || ok
+			}
+		case num == 4 && wire == wireLen:
+			stop.StopID, err = d.string()
+		case num == 5 && wire == wireVarint:
+			var v uint64
+			v, err = d.varint()
+			stop.Skipped = v == 1
+		default:
+			err = d.skip(wire)
+		}
+		if err != nil {
+			return stop, err
+		}
+	}
+	return stop, nil
+}
+
+// parseStopTimeEvent returns the delay of a StopTimeEvent. Events with only an
+// absolute time are ignored, the scheduled time to compare them to isn't
+// known here.
+func parseStopTimeEvent(d *decoder) (time.Duration, bool, error) {
+	var delay time.Duration
+	var ok bool
+	for !d.done() {
+		num, wire, err := d.field()
+		if err != nil {
+			return 0, false, err
+		}
+		if num == 1 && wire == wireVarint {
+			v, err := d.int32()
+			if err != nil {
+				return 0, false, err
+			}
+			delay, ok = time.Duration(v)*time.Second, true
+			continue
+		}
+		if err := d.skip(wire); err != nil {
+			return 0, false, err
+		}
+	}
+	return delay, ok, nil
+}
+
+func parseAlert(d *decoder) (Alert, error) {
+	var alert Alert
+	for !d.done() {
+		num, wire, err := d.field()
+		if err != nil {
+			return alert, err
+		}
+		switch {
+		case num == 1 && wire == wireLen:
+			var m *decoder
+			if m, err = d.message(); err == nil {
+				var period Period
+				period, err = parsePeriod(m)
+				alert.Periods = append(alert.Periods, period)
+			}
+		case num == 5 && wire == wireLen:
+			var m *decoder
+			if m, err = d.message(); err == nil {
+				var entity Entity
+				entity, err = parseEntity(m)
+				alert.Informed = append(alert.Informed, entity)
+			}
+		case num == 7 && wire == wireVarint:
+			var v uint64
+			v, err = d.varint()
+			alert.Effect = Effect(v)
+		case num == 10 && wire == wireLen:
+			var m *decoder
+			if m, err = d.message(); err == nil {
+				alert.Header, err = parseTranslatedString(m)
+			}
+		case num == 11 && wire == wireLen:
+			var m *decoder
+			if m, err = d.message(); err == nil {
+				alert.Description, err = parseTranslatedString(m)
+			}
+		default:
+			err = d.skip(wire)
+		}
+		if err != nil {
+			return alert, err
+		}
+	}
+	return alert, nil
+}
+
+func parsePeriod(d *decoder) (Period, error) {
+	var period Period
+	for !d.done() {
+		num, wire, err := d.field()
+		if err != nil {
+			return period, err
+		}
+		if (num == 1 || num == 2) && wire == wireVarint {
+			v, err := d.varint()
+			if err != nil {
+				return period, err
+			}
+			if num == 1 {
+				period.Start = time.Unix(int64(v), 0)
+			} else {
+				period.End = time.Unix(int64(v), 0)
+			}
+			continue
+		}
+		if err := d.skip(wire); err != nil {
+			return period, err
+		}
+	}
+	return period, nil
+}
+
+func parseEntity(d *decoder) (Entity, error) {
+	var entity Entity
+	for !d.done() {
+		num, wire, err := d.field()
+		if err != nil {
+			return entity, err
+		}
+		switch {
+		case num == 2 && wire == wireLen:
+			entity.RouteID, err = d.string()
+		case num == 4 && wire == wireLen:
+			var m *decoder
+			if m, err = d.message(); err == nil {
+				var trip tripDescriptor
+				trip, err = parseTripDescriptor(m)
+				entity.TripID = trip.tripID
+				if entity.RouteID == "" {
+					entity.RouteID = trip.routeID
+				}
+			}
+		case num == 5 && wire == wireLen:
+			entity.StopID, err = d.string()
+		default:
+			err = d.skip(wire)
+		}
+		if err != nil {
+			return entity, err
+		}
+	}
+	return entity, nil
+}
+
+// parseTranslatedString returns the first translation, feeds that translate
+// list the untranslated text first.
+func parseTranslatedString(d *decoder) (string, error) {
+	var text string
+	for !d.done() {
+		num, wire, err := d.field()
+		if err != nil {
+			return "", err
+		}
+		if num == 1 && wire == wireLen && text == "" {
+			m, err := d.message()
+			if err != nil {
+				return "", err
+			}
+			for !m.done() {
+				num, wire, err := m.field()
+				if err != nil {
+					return "", err
+				}
+				if num == 1 && wire == wireLen {
+					if text, err = m.string(); err != nil {
+						return "", err
+					}
+					continue
+				}
+				if err := m.skip(wire); err != nil {
+					return "", err
+				}
+			}
+			continue
+		}
+		if err := d.skip(wire); err != nil {
+			return "", err
+		}
+	}
+	return text, nil
+}
@@ -0,0 +1,283 @@
+package realtime
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/vincbro/pascal/blaise"
+)
+
+func parseFixture(t *testing.T, name string) *Feed {
+	t.Helper()
+	feed, err := Parse(readFixture(t, name))
+	if err != nil {
+		t.Fatalf("failed to parse %s: %v", name, err)
+	}
+	if !feed.Timestamp.Equal(feedTime) {
+		t.Errorf("feed from %s, want %s", feed.Timestamp, feedTime)
+	}
+	return feed
+}
+
+func TestParseTripUpdates(t *testing.T) {
+	feed := parseFixture(t, "trip_updates.pb")
+	if len(feed.Alerts) != 0 {
+		t.Errorf("got %d alerts in a trip update feed", len(feed.Alerts))
+	}
+	// The deleted entity is dropped
+	want := []TripUpdate{
+		{
+			TripID: "t19", RouteID: "r19", StartDate: "20261019",
+			StopTimes: []StopTimeUpdate{
+				{StopSequence: 1, StopID: "740021666", DepartureDelay: 2 * time.Minute, HasDelay: true},
+				{StopSequence: 2, StopID: "740021664", ArrivalDelay: 3 * time.Minute, HasDelay: true},
+			},
+		},
+		{TripID: "t14", RouteID: "r14", StartDate: "20261019", Delay: 5 * time.Minute, HasDelay: true},
+		{
+			TripID: "t515", RouteID: "r515", StartDate: "20261019",
+			StopTimes: []StopTimeUpdate{{StopID: "740000001", DepartureDelay: -time.Minute, HasDelay: true}},
+		},
+		{TripID: "t4", RouteID: "r4", StartDate: "20261019", Canceled: true},
+		{
+			TripID: "t17", RouteID: "r17", StartDate: "20261019",
+			StopTimes: []StopTimeUpdate{{StopID: "740021666", Skipped: true}},
+		},
+	}
+	if len(feed.TripUpdates) != len(want) {
+		t.Fatalf("got %d trip updates, want %d", len(feed.TripUpdates), len(want))
+	}
+	for i, update := range feed.TripUpdates {
+		w := want[i]
+		if update.TripID != w.TripID || update.RouteID != w.RouteID || update.StartDate != w.StartDate ||
+			update.Canceled != w.Canceled || update.Delay != w.Delay || update.HasDelay != w.HasDelay ||
+			!slices.Equal(update.StopTimes, w.StopTimes) {
+			t.Errorf("trip update %d is %+v, want %+v", i, update, w)
+		}
+	}
+}
+
+func TestParseAlerts(t *testing.T) {
+	feed := parseFixture(t, "alerts.pb")
+	if len(feed.TripUpdates) != 0 {
+		t.Errorf("got %d trip updates in an alert feed", len(feed.TripUpdates))
+	}
+	want := []Alert{
+		{
+			ID: "line", Effect: EffectSignificantDelays,
+			Header: "Delays on line 19", Description: "A signal fault at Odenplan",
+			Periods:  []Period{{Start: feedTime, End: feedTime.Add(4 * time.Hour)}},
+			Informed: []Entity{{RouteID: "r19"}},
+		},
+		{
+			ID: "stop", Effect: EffectStopMoved, Header: "Bus stop moved",
+			Informed: []Entity{{StopID: "740000001"}},
+		},
+		{
+			ID: "trip", Effect: EffectNoService, Header: "Departure cancelled",
+			Informed: []Entity{{TripID: "t14", RouteID: "r14"}},
+		},
+	}
+	if len(feed.Alerts) != len(want) {
+		t.Fatalf("got %d alerts, want %d", len(feed.Alerts), len(want))
+	}
+	for i, alert := range feed.Alerts {
+		w := want[i]
+		samePeriods := slices.EqualFunc(alert.Periods, w.Periods, func(a, b Period) bool {
+			return a.Start.Equal(b.Start) && a.End.Equal(b.End)
+		})
+		if alert.ID != w.ID || alert.Effect != w.Effect || alert.Header != w.Header || alert.Description != w.Description ||
+			!samePeriods || !slices.Equal(alert.Informed, w.Informed) {
+			t.Errorf("alert %d is %+v, want %+v", i, alert, w)
+		}
+	}
+
+	line := feed.Alerts[0]
+	if line.Active(feedTime.Add(-time.Second)) || !line.Active(feedTime) || line.Active(feedTime.Add(4*time.Hour)) {
+		t.Error("line alert is active outside of its period")
+	}
+	if !feed.Alerts[1].Active(feedTime.AddDate(1, 0, 0)) {
+		t.Error("alert without periods is not always active")
+	}
+}
+
+func transitLeg(tripID, routeID, from, to string, stops ...string) blaise.Leg {
+	leg := blaise.Leg{
+		From:    blaise.Location{ID: from},
+		To:      blaise.Location{ID: to},
+		Mode:    "Subway",
+		TripID:  &tripID,
+		RouteID: &routeID,
+	}
+	for _, stop := range stops {
+		leg.Stops = append(leg.Stops, blaise.Stop{Location: blaise.Location{ID: stop}})
+	}
+	return leg
+}
+
+func TestStatus(t *testing.T) {
+	updates := parseFixture(t, "trip_updates.pb")
+	alerts := parseFixture(t, "alerts.pb")
+	feed := &Feed{TripUpdates: updates.TripUpdates, Alerts: alerts.Alerts}
+	now := feedTime.Add(time.Hour)
+
+	tests := []struct {
+		name      string
+		legs      []blaise.Leg
+		date      string
+		found     bool
+		delay     time.Duration
+		canceled  bool
+		alertsIDs []string
+	}{
+		{
+			name:      "delayed first leg with a line alert",
+			legs:      []blaise.Leg{transitLeg("t19", "r19", "740021666", "740000001", "740021666", "740021664", "740000001")},
+			date:      "2026-10-19",
+			found:     true,
+			delay:     2 * time.Minute,
+			alertsIDs: []string{"line", "stop"},
+		},
+		{
+			name:      "boarding at a stop with an arrival delay",
+			legs:      []blaise.Leg{transitLeg("t19", "r19", "740021664", "740000001")},
+			date:      "2026-10-19",
+			found:     true,
+			delay:     3 * time.Minute,
+			alertsIDs: []string{"line", "stop"},
+		},
+		{
+			// The last update before the boarding stop carries on to it
+			name:      "boarding after the updated stops",
+			legs:      []blaise.Leg{transitLeg("t19", "r19", "740021665", "740000001", "740021665", "740000001")},
+			date:      "2026-10-19",
+			found:     true,
+			delay:     3 * time.Minute,
+			alertsIDs: []string{"line", "stop"},
+		},
+		{
+			name:      "trip delay and a trip alert",
+			legs:      []blaise.Leg{transitLeg("t14", "r14", "740000001", "740020749")},
+			date:      "2026-10-19",
+			found:     true,
+			delay:     5 * time.Minute,
+			alertsIDs: []string{"stop", "trip"},
+		},
+		{
+			name:      "early departure",
+			legs:      []blaise.Leg{transitLeg("t515", "r515", "740000001", "740021705")},
+			date:      "2026-10-19",
+			found:     true,
+			delay:     -time.Minute,
+			alertsIDs: []string{"stop"},
+		},
+		{
+			name:     "canceled trip",
+			legs:     []blaise.Leg{transitLeg("t4", "r4", "740021666", "740020749")},
+			date:     "2026-10-19",
+			found:    true,
+			canceled: true,
+		},
+		{
+			name:     "skipped boarding stop",
+			legs:     []blaise.Leg{transitLeg("t17", "r17", "740021666", "740020749")},
+			date:     "2026-10-19",
+			found:    true,
+			canceled: true,
+		},
+		{
+			// Only the first transit leg decides the delay
+			name:      "second leg delay",
+			legs:      []blaise.Leg{transitLeg("t515", "r515", "740000001", "740021705"), transitLeg("t14", "r14", "740000001", "740020749")},
+			date:      "2026-10-19",
+			found:     true,
+			delay:     -time.Minute,
+			alertsIDs: []string{"stop", "trip"},
+		},
+		{
+			name: "other service date",
+			legs: []blaise.Leg{transitLeg("t4", "r4", "740021666", "740020749")},
+			date: "2026-10-20",
+		},
+		{
+			name: "deleted entity",
+			legs: []blaise.Leg{transitLeg("t99", "r99", "740021666", "740020749")},
+			date: "2026-10-19",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, found := feed.Status(blaise.Itinerary{Legs: tt.legs}, tt.date, now)
+			if found != tt.found {
+				t.Fatalf("found %v, want %v", found, tt.found)
+			}
+			if status.Delay != tt.delay || status.Canceled != tt.canceled {
+				t.Errorf("delay %v canceled %v, want %v %v", status.Delay, status.Canceled, tt.delay, tt.canceled)
+			}
+			ids := make([]string, 0, len(status.Alerts))
+			for _, alert := range status.Alerts {
+				ids = append(ids, alert.ID)
+			}
+			if !slices.Equal(ids, tt.alertsIDs) {
+				t.Errorf("alerts %v, want %v", ids, tt.alertsIDs)
+			}
+		})
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"truncated tag", []byte{0x80}},
+		{"truncated length", []byte{0x0a}},
+		{"length past the end", message{}.tag(1, wireLen).raw(10).raw(1)},
+		{"huge length", message{}.tag(2, wireLen).raw(1 << 62)},
+		{"varint over 64 bits", append(message{}.tag(5, wireVarint), bytes.Repeat([]byte{0xff}, 10)...)},
+		{"group wire type", message{}.tag(9, 3)},
+		{"truncated fixed32", message{}.tag(9, wireI32).raw(1)},
+		{"truncated fixed64", message{}.tag(9, wireI64).raw(1)},
+		{"truncated entity", message{}.bytes(2, message{}.string(1, "x").tag(3, wireLen).raw(5))},
+		{"truncated header", message{}.bytes(1, message{}.tag(3, wireVarint))},
+		{"truncated stop time", message{}.bytes(2, message{}.bytes(3, message{}.bytes(2, message{}.tag(4, wireLen).raw(3))))},
+		{"truncated alert text", message{}.bytes(2, message{}.bytes(5, message{}.bytes(10, message{}.bytes(1, message{}.tag(1, wireLen)))))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.data); err == nil {
+				t.Error("parsed a malformed feed")
+			}
+		})
+	}
+
+	// Cutting a feed anywhere must fail or give a shorter feed, never panic
+	for name := range fixtures {
+		data := readFixture(t, name)
+		for n := range len(data) {
+			feed, err := Parse(data[:n])
+			if err == nil && feed == nil {
+				t.Errorf("%s cut at %d gave no feed and no error", name, n)
+			}
+		}
+		if _, err := Parse(data[:len(data)-1]); !errors.Is(err, errTruncated) {
+			t.Errorf("%s without its last byte gave %v, want a truncated error", name, err)
+		}
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	for _, build := range fixtures {
+		f.Add([]byte(build()))
+	}
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		feed, err := Parse(data)
+		if err == nil && feed == nil {
+			t.Error("no feed and no error")
+		}
+	})
+}
@@ -0,0 +1,168 @@
+package realtime
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// The .pb files in testdata are GTFS-RT feeds as a producer would publish
+// them. They are written by the encoder below, run
+//
+//	go test ./realtime -run TestFixtures -update
+//
+// after changing what they contain.
+var update = flag.Bool("update", false, "rewrite the .pb fixtures in testdata")
+
+// feedTime is the header timestamp of both fixtures.
+var feedTime = time.Date(2026, 10, 19, 6, 0, 0, 0, time.UTC)
+
+// message encodes a protobuf message field by field.
+type message []byte
+
+func (m message) tag(num, wire int) message {
+	return m.raw(uint64(num)<<3 | uint64(wire))
+}
+
+// raw appends v as a varint without a tag.
+func (m message) raw(v uint64) message {
+	for v >= 0x80 {
+		m = append(m, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(m, byte(v))
+}
+
+func (m message) varint(num int, v uint64) message {
+	return m.tag(num, wireVarint).raw(v)
+}
+
+// int32 encodes v the way protobuf does, negative values sign extended to
+// ten bytes.
+func (m message) int32(num int, v int32) message {
+	return m.varint(num, uint64(int64(v)))
+}
+
+func (m message) bytes(num int, b []byte) message {
+	return append(m.tag(num, wireLen).raw(uint64(len(b))), b...)
+}
+
+func (m message) string(num int, s string) message {
+	return m.bytes(num, []byte(s))
+}
+
+func (m message) fixed32(num int, v uint32) message {
+	return append(m.tag(num, wireI32), byte(v), byte(v>>8), byte(v>>16), byte(v>>24))
+}
+
+func header() message {
+	return message{}.string(1, "2.0").varint(3, uint64(feedTime.Unix()))
+}
+
+func translated(texts ...string) message {
+	var m message
+	for _, text := range texts {
+		m = m.bytes(1, message{}.string(1, text))
+	}
+	return m
+}
+
+// tripUpdatesFeed has a delayed, an early, a canceled and a skipping trip,
+// plus an entity that was deleted.
+func tripUpdatesFeed() message {
+	descriptor := func(tripID, routeID string) message {
+		return message{}.string(1, tripID).string(3, "20261019").string(5, routeID)
+	}
+	event := func(delay int32) message {
+		return message{}.int32(1, delay).varint(2, uint64(feedTime.Unix()))
+	}
+	entities := []message{
+		// The 19 is two minutes late from Odenplan and three at the next stop
+		message{}.string(1, "delayed").bytes(3, message{}.
+			bytes(1, descriptor("t19", "r19")).
+			bytes(2, message{}.varint(1, 1).string(4, "740021666").bytes(3, event(120))).
+			bytes(2, message{}.varint(1, 2).string(4, "740021664").bytes(2, event(180))).
+			varint(4, uint64(feedTime.Unix())).
+			fixed32(99, 7)),
+		// The 14 has only a delay for the whole trip
+		message{}.string(1, "trip-delay").bytes(3, message{}.
+			bytes(1, descriptor("t14", "r14")).
+			int32(5, 300)),
+		// The 515 leaves Centralen a minute early
+		message{}.string(1, "early").bytes(3, message{}.
+			bytes(1, descriptor("t515", "r515")).
+			bytes(2, message{}.string(4, "740000001").bytes(3, event(-60)))),
+		message{}.string(1, "canceled").bytes(3, message{}.
+			bytes(1, descriptor("t4", "r4").varint(4, 3))),
+		message{}.string(1, "skipped").bytes(3, message{}.
+			bytes(1, descriptor("t17", "r17")).
+			bytes(2, message{}.string(4, "740021666").varint(5, 1))),
+		message{}.string(1, "deleted").varint(2, 1).bytes(3, message{}.
+			bytes(1, descriptor("t99", "r99"))),
+	}
+	feed := message{}.bytes(1, header())
+	for _, entity := range entities {
+		feed = feed.bytes(2, entity)
+	}
+	return feed
+}
+
+// alertsFeed has an alert for a line during the morning, one for a stop and
+// one for a single trip.
+func alertsFeed() message {
+	start, end := feedTime, feedTime.Add(4*time.Hour)
+	entities := []message{
+		message{}.string(1, "line").bytes(5, message{}.
+			bytes(1, message{}.varint(1, uint64(start.Unix())).varint(2, uint64(end.Unix()))).
+			bytes(5, message{}.string(1, "SL").string(2, "r19")).
+			varint(7, uint64(EffectSignificantDelays)).
+			bytes(10, translated("Delays on line 19", "Förseningar på linje 19")).
+			bytes(11, translated("A signal fault at Odenplan"))),
+		message{}.string(1, "stop").bytes(5, message{}.
+			bytes(5, message{}.string(5, "740000001")).
+			varint(7, uint64(EffectStopMoved)).
+			bytes(10, translated("Bus stop moved"))),
+		message{}.string(1, "trip").bytes(5, message{}.
+			bytes(5, message{}.bytes(4, message{}.string(1, "t14").string(5, "r14"))).
+			varint(7, uint64(EffectNoService)).
+			bytes(10, translated("Departure cancelled"))),
+	}
+	feed := message{}.bytes(1, header())
+	for _, entity := range entities {
+		feed = feed.bytes(2, entity)
+	}
+	return feed
+}
+
+var fixtures = map[string]func() message{
+	"trip_updates.pb": tripUpdatesFeed,
+	"alerts.pb":       alertsFeed,
+}
+
+// readFixture reads a feed from testdata.
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	return data
+}
+
+// TestFixtures checks the fixtures match the encoder, so they can't drift
+// from what the tests expect.
+func TestFixtures(t *testing.T) {
+	for name, build := range fixtures {
+		path := filepath.Join("testdata", name)
+		if *update {
+			if err := os.WriteFile(path, build(), 0o644); err != nil {
+				t.Fatalf("failed to write %s: %v", path, err)
+			}
+		}
+		if string(readFixture(t, name)) != string(build()) {
+			t.Errorf("%s is out of date, run with -update", path)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package realtime
+
+import (
+	"strings"
+	"time"
+
+	"github.com/vincbro/pascal/blaise"
+)
+
+// Status is what the realtime feeds say about an itinerary.
+type Status struct {
+	// Delay is the predicted departure delay of the first transit leg
+	Delay time.Duration
+	// Canceled is set if any transit leg won't run, or won't stop where it
+	// should be boarded
+	Canceled bool
+	// Alerts are the active service alerts for the lines, trips and stops of
+	// the itinerary
+	Alerts []Alert
+}
+
+// Status matches the itinerary, running on serviceDate (YYYY-MM-DD), against
+// the feed. It reports false if nothing in the feed applies to it.
+func (f *Feed) Status(itinerary blaise.Itinerary, serviceDate string, now time.Time) (Status, bool) {
+	var status Status
+	if f == nil {
+		return status, false
+	}
+	found := false
+	first := true
+	seen := make(map[string]bool)
+	startDate := strings.ReplaceAll(serviceDate, "-", "")
+	for _, leg := range itinerary.Legs {
+		if !leg.IsTransit() {
+			continue
+		}
+		if update, ok := f.tripUpdate(leg, startDate); ok {
+			found = true
+			delay, canceled := legDelay(update, leg)
+			status.Canceled = status.Canceled || canceled
+			if first {
+				status.Delay = delay
+			}
+		}
+		first = false
+		for _, alert := range f.Alerts {
+			if seen[alert.ID+alert.Header] || !alert.Active(now) || !alert.about(leg) {
+				continue
+			}
+			seen[alert.ID+alert.Header] = true
+			status.Alerts = append(status.Alerts, alert)
+			found = true
+		}
+	}
+	return status, found
+}
+
+func (f *Feed) tripUpdate(leg blaise.Leg, startDate string) (TripUpdate, bool) {
+	if leg.TripID == nil {
+		return TripUpdate{}, false
+	}
+	for _, update := range f.TripUpdates {
+		if update.TripID == *leg.TripID && (update.StartDate == "" || update.StartDate == startDate) {
+			return update, true
+		}
+	}
+	return TripUpdate{}, false
+}
+
+// legDelay finds the departure delay where the leg is boarded. Stop time
+// updates are ordered along the trip and a delay carries on until the next
+// update, so the last update before the boarding stop applies if it has none
+// of its own.
+func legDelay(update TripUpdate, leg blaise.Leg) (time.Duration, bool) {
+	if update.Canceled {
+		return 0, true
+	}
+	later := make(map[string]bool, len(leg.Stops)+1)
+	for _, stop := range leg.Stops {
+		if stop.Location.ID != leg.From.ID {
+			later[stop.Location.ID] = true
+		}
+	}
+	later[leg.To.ID] = true
+
+	delay := update.Delay
+	for _, stop := range update.StopTimes {
+		if stop.StopID == leg.From.ID {
+			if stop.Skipped {
+				return 0, true
+			}
+			if stop.HasDelay {
+				return stop.Delay(), false
+			}
+			break
+		}
+		if later[stop.StopID] {
+			break
+		}
+		if stop.HasDelay {
+			delay = stop.Delay()
+		}
+	}
+	return delay, false
+}
+
+// about reports whether any of the informed entities of the alert matches
+// the leg.
+func (a Alert) about(leg blaise.Leg) bool {
+	for _, entity := range a.Informed {
+		if entity.RouteID == "" && entity.TripID == "" && entity.StopID == "" {
+			continue
+		}
+		if entity.RouteID != "" && (leg.RouteID == nil || *leg.RouteID != entity.RouteID) {
+			continue
+		}
+		if entity.TripID != "" && (leg.TripID == nil || *leg.TripID != entity.TripID) {
+			continue
+		}
+		if entity.StopID != "" && entity.StopID != leg.From.ID && entity.StopID != leg.To.ID {
+			continue
+		}
+		return true
+	}
+	return false
+}
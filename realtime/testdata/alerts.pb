@@ -0,0 +1,20 @@
+
+
+2.0w
+line*o
+*	
+SLr198R2
+
+Delays on line 19
+
+Förseningar på linje 19Z
+
+A signal fault at Odenplan+
+stop*#**	7400000018	R
+
+Bus stop moved1
+trip*)*"
+
+t14*r148R
+
+Departure cancelled
\ No newline at end of file
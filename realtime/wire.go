@@ -0,0 +1,107 @@
+package realtime
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The feeds are decoded by hand, GTFS-RT only needs a handful of protobuf wire
+// types and pulling in a protobuf runtime for them isn't worth it.
+
+const (
+	wireVarint = 0
+	wireI64    = 1
+	wireLen    = 2
+	wireI32    = 5
+)
+
+var errTruncated = errors.New("truncated message")
+
+// decoder reads the fields of a single protobuf message.
+type decoder struct {
+	buf []byte
+}
+
+func (d *decoder) done() bool {
+	return len(d.buf) == 0
+}
+
+// field reads the tag of the next field.
+func (d *decoder) field() (int, int, error) {
+	tag, err := d.varint()
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(tag >> 3), int(tag & 7), nil
+}
+
+func (d *decoder) varint() (uint64, error) {
+	var v uint64
+	for shift := 0; shift < 64; shift += 7 {
+		if len(d.buf) == 0 {
+			return 0, errTruncated
+		}
+		b := d.buf[0]
+		d.buf = d.buf[1:]
+		v |= uint64(b&0x7f) << shift
+		if b < 0x80 {
+			return v, nil
+		}
+	}
+	return 0, errors.New("varint overflows 64 bits")
+}
+
+// int32 reads an int32 field, negative values are sign extended to ten bytes
+// on the wire.
+func (d *decoder) int32() (int32, error) {
+	v, err := d.varint()
+	return int32(int64(v)), err
+}
+
+func (d *decoder) bytes() ([]byte, error) {
+	n, err := d.varint()
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(d.buf)) < n {
+		return nil, errTruncated
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b, nil
+}
+
+func (d *decoder) string() (string, error) {
+	b, err := d.bytes()
+	return string(b), err
+}
+
+// message returns a decoder for an embedded message.
+func (d *decoder) message() (*decoder, error) {
+	b, err := d.bytes()
+	return &decoder{buf: b}, err
+}
+
+// skip drops a field that isn't needed.
+func (d *decoder) skip(wireType int) error {
+	var n int
+	switch wireType {
+	case wireVarint:
+		_, err := d.varint()
+		return err
+	case wireLen:
+		_, err := d.bytes()
+		return err
+	case wireI64:
+		n = 8
+	case wireI32:
+		n = 4
+	default:
+		return fmt.Errorf("unsupported wire type %d", wireType)
+	}
+	if len(d.buf) < n {
+		return errTruncated
+	}
+	d.buf = d.buf[n:]
+	return nil
+}
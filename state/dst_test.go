@@ -0,0 +1,87 @@
+package state
+
+import (
+	"testing"
+	"time"
+)
+
+// TestServiceDays runs the fixture commute, leaving 08:00 with alerts 15 and
+// 5 minutes before, through the night into the user's morning. The clock
+// runs in UTC so nothing can lean on the zone of the server or the clock.
+func TestServiceDays(t *testing.T) {
+	tests := []struct {
+		name  string
+		zone  string
+		start time.Time
+		// alerts are the instants of the depart soon alerts
+		alerts      []time.Time
+		serviceDate string
+	}{
+		{
+			// Clocks go forward from 02:00 to 03:00 CET, 08:00 is UTC+2
+			name:        "summer time starts",
+			zone:        stockholm,
+			start:       time.Date(2026, 3, 28, 22, 30, 0, 0, time.UTC),
+			alerts:      []time.Time{time.Date(2026, 3, 29, 5, 45, 0, 0, time.UTC), time.Date(2026, 3, 29, 5, 55, 0, 0, time.UTC)},
+			serviceDate: "2026-03-29",
+		},
+		{
+			// Clocks go back from 03:00 to 02:00 CEST, 08:00 is UTC+1
+			name:        "summer time ends",
+			zone:        stockholm,
+			start:       time.Date(2026, 10, 24, 21, 30, 0, 0, time.UTC),
+			alerts:      []time.Time{time.Date(2026, 10, 25, 6, 45, 0, 0, time.UTC), time.Date(2026, 10, 25, 6, 55, 0, 0, time.UTC)},
+			serviceDate: "2026-10-25",
+		},
+		{
+			// Already the 19th in Tokyo while it is still the 18th in New York
+			name:        "user zone ahead of the server",
+			zone:        "Asia/Tokyo",
+			start:       time.Date(2026, 10, 18, 20, 0, 0, 0, time.UTC),
+			alerts:      []time.Time{time.Date(2026, 10, 18, 22, 45, 0, 0, time.UTC), time.Date(2026, 10, 18, 22, 55, 0, 0, time.UTC)},
+			serviceDate: "2026-10-19",
+		},
+	}
+
+	local := time.Local
+	time.Local = mustLocation(t, "America/New_York")
+	t.Cleanup(func() { time.Local = local })
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, tt.start)
+			env.user.TimeZone = tt.zone
+			if err := env.state.DB.UpdateUser(env.user); err != nil {
+				t.Fatalf("failed to update user: %v", err)
+			}
+			trip := env.addCommute(t, "commute", 15*60, 5*60)
+			env.runScheduler(t)
+
+			meta, ok := env.state.Meta.Get(trip.ID)
+			if !ok || meta.ServiceDate != tt.serviceDate {
+				t.Fatalf("scheduled for %q, want %s", meta.ServiceDate, tt.serviceDate)
+			}
+
+			alerts := make([]time.Time, 0, len(tt.alerts))
+			end := tt.alerts[len(tt.alerts)-1].Add(time.Hour)
+			for env.clock.Now().Before(end) {
+				for _, request := range env.step(time.Minute) {
+					if request.ServiceDate != tt.serviceDate {
+						t.Errorf("request %d on %s, want %s", request.Kind, request.ServiceDate, tt.serviceDate)
+					}
+					if request.Kind == RequestDepartSoon {
+						alerts = append(alerts, env.clock.Now())
+					}
+				}
+			}
+			if len(alerts) != len(tt.alerts) {
+				t.Fatalf("got alerts at %v, want %v", alerts, tt.alerts)
+			}
+			for i := range alerts {
+				if !alerts[i].Equal(tt.alerts[i]) {
+					t.Errorf("alert %d at %s, want %s", i, alerts[i].UTC(), tt.alerts[i])
+				}
+			}
+		})
+	}
+}
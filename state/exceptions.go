@@ -0,0 +1,125 @@
+package state
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/vincbro/pascal/calendar"
+	"github.com/vincbro/pascal/database"
+)
+
+// Exceptions are the vacation, away ranges and skip dates of a user, loaded
+// once so any number of trips and days can be checked against them.
+type Exceptions struct {
+	vacation bool
+	aways    []*database.Away
+	// skips are the skipped days by trip ID
+	skips    map[string]map[string]bool
+	calendar *calendar.Calendar
+}
+
+// Exceptions loads the exceptions of the user. What fails to load is logged
+// and left out.
+func (s *State) Exceptions(userID string) *Exceptions {
+	e := &Exceptions{skips: make(map[string]map[string]bool), calendar: s.Calendar}
+
+	user, err := s.DB.GetUser(userID)
+	if err != nil {
+		slog.Error("failed to get user", "user", userID, "error", err)
+	} else {
+		e.vacation = user.Vacation
+	}
+
+	skips, err := s.DB.GetUserSkipDates(userID)
+	if err != nil {
+		slog.Error("failed to get skip dates", "user", userID, "error", err)
+	}
+	for _, skip := range skips {
+		if e.skips[skip.TripID] == nil {
+			e.skips[skip.TripID] = make(map[string]bool)
+		}
+		e.skips[skip.TripID][skip.Date] = true
+	}
+
+	e.aways, err = s.DB.GetAways(userID)
+	if err != nil {
+		slog.Error("failed to get away ranges", "user", userID, "error", err)
+	}
+	return e
+}
+
+// SkipReason reports why the trip does not run on the day of date, if it
+// doesn't. Vacations, pauses, skip dates and away ranges apply to every trip,
+// holidays only to repeating ones.
+func (s *State) SkipReason(trip *database.Trip, date time.Time) (string, bool) {
+	return s.Exceptions(trip.UserID).SkipReason(trip, date)
+}
+
+// UpcomingSkips lists the days within the coming days the trip would have run
+// on but is skipped, with the reason why.
+func (s *State) UpcomingSkips(trip *database.Trip, now time.Time, days int) []Skip {
+	return s.Exceptions(trip.UserID).UpcomingSkips(trip, now, days)
+}
+
+// SkipReason is State.SkipReason for a trip of the user the exceptions are of.
+func (e *Exceptions) SkipReason(trip *database.Trip, date time.Time) (string, bool) {
+	if e.vacation {
+		return "Vacation", true
+	}
+	if trip.PausedOn(date) {
+		return "Paused", true
+	}
+	if e.skips[trip.ID][date.Format(serviceDateLayout)] {
+		return "Skipped", true
+	}
+	for _, away := range e.aways {
+		if away.Covers(date) {
+			if away.Reason != "" {
+				return fmt.Sprintf("Away: %s", away.Reason), true
+			}
+			return "Away", true
+		}
+	}
+	if trip.Date == "" {
+		if name, ok := e.calendar.Holiday(date); ok {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// UpcomingSkips is State.UpcomingSkips for a trip of the user the exceptions
+// are of.
+func (e *Exceptions) UpcomingSkips(trip *database.Trip, now time.Time, days int) []Skip {
+	skipped := make([]Skip, 0)
+	for i := range days {
+		day := time.Date(now.Year(), now.Month(), now.Day()+i, 0, 0, 0, 0, now.Location())
+		if !trip.RunsOn(day) {
+			continue
+		}
+		if reason, ok := e.SkipReason(trip, day); ok {
+			skipped = append(skipped, Skip{Date: day, Reason: reason})
+		}
+	}
+	return skipped
+}
+
+type Skip struct {
+	Date   time.Time
+	Reason string
+}
+
+// skipped reports whether the occurrence of trip on serviceDate should be
+// kept quiet.
+func (s *State) skipped(trip *database.Trip, serviceDate string, now time.Time) bool {
+	day, err := time.ParseInLocation(serviceDateLayout, serviceDate, now.Location())
+	if err != nil {
+		return false
+	}
+	reason, ok := s.SkipReason(trip, day)
+	if ok {
+		slog.Debug("Skipped occurrence", "name", trip.Name, "date", serviceDate, "reason", reason)
+	}
+	return ok
+}
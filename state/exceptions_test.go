@@ -0,0 +1,176 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/vincbro/pascal/calendar"
+	"github.com/vincbro/pascal/database"
+)
+
+// addExceptions gives the env a holiday on 2026-10-23, an away range over
+// 2026-10-26 and 27, a day away for no reason on 2026-10-29 and skips trip on
+// 2026-10-21.
+func (e *testEnv) addExceptions(t *testing.T, trip *database.Trip) {
+	t.Helper()
+	holidays, err := calendar.New([]calendar.Holiday{{Name: "Founders Day", Date: "2026-10-23"}})
+	if err != nil {
+		t.Fatalf("failed to build calendar: %v", err)
+	}
+	e.state.Calendar = holidays
+	aways := []*database.Away{
+		{UserID: e.user.ID, StartDate: "2026-10-26", EndDate: "2026-10-27", Reason: "Conference"},
+		{UserID: e.user.ID, StartDate: "2026-10-29", EndDate: "2026-10-29"},
+		{UserID: "someone else", StartDate: "2026-10-19", EndDate: "2026-11-30"},
+	}
+	for _, away := range aways {
+		if err := e.state.DB.AddAway(away); err != nil {
+			t.Fatalf("failed to add away: %v", err)
+		}
+	}
+	if err := e.state.DB.AddSkipDate(&database.SkipDate{TripID: trip.ID, Date: "2026-10-21"}); err != nil {
+		t.Fatalf("failed to add skip date: %v", err)
+	}
+}
+
+func TestSkipReason(t *testing.T) {
+	loc := mustLocation(t, stockholm)
+	env := newTestEnv(t, time.Date(2026, 10, 19, 7, 0, 0, 0, loc))
+	trip := env.addCommute(t, "commute")
+	env.addExceptions(t, trip)
+
+	tests := []struct {
+		date   string
+		reason string
+	}{
+		{"2026-10-20", ""},
+		{"2026-10-21", "Skipped"},
+		{"2026-10-23", "Founders Day"},
+		{"2026-10-26", "Away: Conference"},
+		{"2026-10-27", "Away: Conference"},
+		{"2026-10-28", ""},
+		{"2026-10-29", "Away"},
+	}
+	for _, tt := range tests {
+		date, _ := time.ParseInLocation(database.DateLayout, tt.date, loc)
+		reason, ok := env.state.SkipReason(trip, date.Add(8*time.Hour))
+		if reason != tt.reason || ok != (tt.reason != "") {
+			t.Errorf("%s is skipped for %q, %v, want %q", tt.date, reason, ok, tt.reason)
+		}
+	}
+
+	// Holidays only keep repeating trips at home
+	once := *trip
+	once.Date = "2026-10-23"
+	if reason, ok := env.state.SkipReason(&once, time.Date(2026, 10, 23, 8, 0, 0, 0, loc)); ok {
+		t.Errorf("one-off trip is skipped on a holiday for %q", reason)
+	}
+
+	trip.Paused, trip.PausedUntil = true, "2026-10-21"
+	if reason, _ := env.state.SkipReason(trip, time.Date(2026, 10, 20, 8, 0, 0, 0, loc)); reason != "Paused" {
+		t.Errorf("paused trip is skipped for %q, want Paused", reason)
+	}
+	if _, ok := env.state.SkipReason(trip, time.Date(2026, 10, 22, 8, 0, 0, 0, loc)); ok {
+		t.Error("trip is still paused after its pause ended")
+	}
+
+	// A vacation comes before anything else
+	env.user.Vacation = true
+	if err := env.state.DB.UpdateUser(env.user); err != nil {
+		t.Fatalf("failed to update user: %v", err)
+	}
+	for _, tt := range tests {
+		date, _ := time.ParseInLocation(database.DateLayout, tt.date, loc)
+		if reason, _ := env.state.SkipReason(trip, date); reason != "Vacation" {
+			t.Errorf("%s is skipped for %q on vacation", tt.date, reason)
+		}
+	}
+}
+
+func TestUpcomingSkips(t *testing.T) {
+	loc := mustLocation(t, stockholm)
+	now := time.Date(2026, 10, 19, 21, 0, 0, 0, loc)
+	env := newTestEnv(t, now)
+	trip := env.addCommute(t, "commute")
+	trip.Recurrence = database.WeeklyOn(time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday)
+	env.addExceptions(t, trip)
+
+	// The holiday on a Saturday is no skip, the trip doesn't run anyway
+	env.state.Calendar, _ = calendar.New([]calendar.Holiday{
+		{Name: "Founders Day", Date: "2026-10-23"},
+		{Name: "All Saints' Day", Date: "2026-10-31"},
+	})
+	want := []struct{ date, reason string }{
+		{"2026-10-21", "Skipped"},
+		{"2026-10-23", "Founders Day"},
+		{"2026-10-26", "Away: Conference"},
+		{"2026-10-27", "Away: Conference"},
+		{"2026-10-29", "Away"},
+	}
+	got := env.state.UpcomingSkips(trip, now, 14)
+	if len(got) != len(want) {
+		t.Fatalf("got %d skips %v, want %d", len(got), got, len(want))
+	}
+	for i, skip := range got {
+		if skip.Date.Format(database.DateLayout) != want[i].date || skip.Reason != want[i].reason {
+			t.Errorf("skip %d is %s for %q, want %s for %q", i, skip.Date.Format(database.DateLayout), skip.Reason, want[i].date, want[i].reason)
+		}
+		if skip.Date.Location() != loc || skip.Date.Hour() != 0 {
+			t.Errorf("skip %d is at %s, want midnight in %s", i, skip.Date, loc)
+		}
+	}
+
+	if skips := env.state.UpcomingSkips(trip, now, 2); len(skips) != 0 {
+		t.Errorf("got skips %v within the next 2 days", skips)
+	}
+}
+
+func TestExceptionsLoadOnce(t *testing.T) {
+	loc := mustLocation(t, stockholm)
+	now := time.Date(2026, 10, 19, 21, 0, 0, 0, loc)
+	env := newTestEnv(t, now)
+	trips := []*database.Trip{env.addCommute(t, "commute"), env.addCommute(t, "second"), env.addCommute(t, "third")}
+	env.addExceptions(t, trips[0])
+	// Skip dates of other trips and users stay with their trip
+	other := *trips[0]
+	other.ID, other.UserID = "other", "someone else"
+	if err := env.state.DB.AddTrip(&other); err != nil {
+		t.Fatalf("failed to add trip: %v", err)
+	}
+	for _, skip := range []database.SkipDate{{TripID: "second", Date: "2026-10-22"}, {TripID: "other", Date: "2026-10-20"}} {
+		if err := env.state.DB.AddSkipDate(&skip); err != nil {
+			t.Fatalf("failed to add skip date: %v", err)
+		}
+	}
+
+	queries := 0
+	err := env.state.DB.Client.Callback().Query().Before("gorm:query").Register("count", func(*gorm.DB) { queries++ })
+	if err != nil {
+		t.Fatalf("failed to count queries: %v", err)
+	}
+	exceptions := env.state.Exceptions(env.user.ID)
+	loaded := queries
+	if loaded != 3 {
+		t.Errorf("loading exceptions took %d queries, want 3", loaded)
+	}
+	got := make(map[string]int)
+	for _, trip := range trips {
+		got[trip.ID] = len(exceptions.UpcomingSkips(trip, now, 14))
+	}
+	if queries != loaded {
+		t.Errorf("checking 14 days of %d trips took %d queries, want none past loading", len(trips), queries-loaded)
+	}
+
+	want := map[string]int{"commute": 5, "second": 5, "third": 4}
+	for id, n := range want {
+		if got[id] != n {
+			t.Errorf("trip %s has %d skips, want %d", id, got[id], n)
+		}
+		trip, _ := env.state.DB.GetTrip(env.user.ID, id)
+		if skips := env.state.UpcomingSkips(trip, now, 14); len(skips) != n {
+			t.Errorf("trip %s has %d skips loaded on its own, want %d", id, len(skips), n)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package state
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/vincbro/pascal/blaise"
+	"github.com/vincbro/pascal/database"
+)
+
+// The fixture night bus leaves Solna centrum at 24:20:00, passes Karolinska
+// at 24:35:00 and is at Odenplan at 24:45:00, quarter to one the next
+// morning.
+const solna = "740021705"
+
+func (e *testEnv) addNightBus(t *testing.T, alerts ...blaise.Time) *database.Trip {
+	t.Helper()
+	itineraries, err := e.state.BClient.Routing(context.Background(), solna, odenplan, "00:20:00", true, 1)
+	if err != nil {
+		t.Fatalf("failed to route night bus: %v", err)
+	}
+	trip := &database.Trip{
+		ID:                "night",
+		UserID:            e.user.ID,
+		Name:              "Home",
+		From:              itineraries[0].From.Name,
+		FromID:            solna,
+		To:                itineraries[0].To.Name,
+		ToID:              odenplan,
+		Time:              "00:20:00",
+		Departure:         true,
+		Recurrence:        database.Recurrence{Freq: database.Daily, Interval: 1},
+		Alerts:            alerts,
+		ExpectedItinerary: itineraries[0],
+	}
+	if err := e.state.DB.AddTrip(trip); err != nil {
+		t.Fatalf("failed to add trip: %v", err)
+	}
+	return trip
+}
+
+func TestPastMidnight(t *testing.T) {
+	loc := mustLocation(t, stockholm)
+	tests := []struct {
+		name  string
+		start time.Time
+		want  []sentRequest
+	}{
+		{
+			// The hour before alert is on the evening of the service day
+			name:  "from the evening",
+			start: time.Date(2026, 10, 19, 22, 0, 0, 0, loc),
+			want:  []sentRequest{{"Oct 19 23:20", RequestDepartSoon}, {"Oct 20 00:35", RequestAlight}, {"Oct 20 00:45", RequestArrival}},
+		},
+		{
+			// Yesterday's service day is still underway after midnight
+			name:  "from after midnight",
+			start: time.Date(2026, 10, 20, 0, 5, 0, 0, loc),
+			want:  []sentRequest{{"Oct 20 00:35", RequestAlight}, {"Oct 20 00:45", RequestArrival}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, tt.start)
+			trip := env.addNightBus(t, 60*60)
+			itinerary := trip.ExpectedItinerary
+			if itinerary.DepartureTime != 24*3600+20*60 {
+				t.Fatalf("night bus leaves at %d, want 24:20:00", itinerary.DepartureTime)
+			}
+			if got := itinerary.DepartureTime.ToHMSString(); got != "00:20:00 (+1)" {
+				t.Errorf("departure renders as %q", got)
+			}
+			if got := itinerary.ArrivalTime.ToHMSString(); got != "00:45:00 (+1)" {
+				t.Errorf("arrival renders as %q", got)
+			}
+			env.runScheduler(t)
+
+			meta, ok := env.state.Meta.Get(trip.ID)
+			if !ok || meta.ServiceDate != "2026-10-19" {
+				t.Fatalf("scheduled for %q, want 2026-10-19", meta.ServiceDate)
+			}
+
+			got := make([]sentRequest, 0)
+			end := time.Date(2026, 10, 20, 1, 30, 0, 0, loc)
+			for env.clock.Now().Before(end) {
+				for _, request := range env.step(time.Minute) {
+					if request.ServiceDate != "2026-10-19" {
+						t.Errorf("request %d at %s is for %s, want 2026-10-19", request.Kind, env.clock.Now(), request.ServiceDate)
+					}
+					got = append(got, sentRequest{at: env.clock.Now().Format("Jan 2 15:04"), kind: request.Kind})
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got requests %v, want %v", got, tt.want)
+			}
+			for i := range tt.want {
+				if got[i] != tt.want[i] {
+					t.Errorf("request %d is %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+			// Tonight's bus is next
+			meta, _ = env.state.Meta.Get(trip.ID)
+			if meta.ServiceDate != "2026-10-20" {
+				t.Errorf("meta is for %q after arrival, want 2026-10-20", meta.ServiceDate)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/vincbro/pascal/realtime"
+)
+
+// realtimeInterval is how often the realtime feeds are polled.
+const realtimeInterval = 30 * time.Second
+
+// realtimeHorizon is how long before departure realtime updates are applied to
+// a trip, predictions further out than that are rarely worth acting on.
+const realtimeHorizon = 2 * time.Hour
+
+// realtimeState is what the realtime feeds last said about the occurrence of
+// a trip on serviceDate, and what the user has already been told.
+type realtimeState struct {
+	serviceDate string
+	delay       time.Duration
+	canceled    bool
+	alerts      map[string]bool
+}
+
+// pollRealtime refreshes the realtime feeds until the state is stopped and
+// has the scheduler apply them after every refresh.
+func (s *State) pollRealtime() {
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), realtimeInterval)
+		if err := s.Realtime.Refresh(ctx, s.Clock.Now()); err != nil {
+			slog.Warn("failed to refresh realtime feeds", "error", err)
+		}
+		cancel()
+		select {
+		case s.realtimeTick <- struct{}{}:
+		default:
+		}
+
+		select {
+		case <-s.Clock.After(realtimeInterval):
+		case <-s.kill:
+			return
+		}
+	}
+}
+
+// applyRealtime matches the latest realtime feeds against every trip that
+// leaves within realtimeHorizon. A changed delay moves the trip's alerts,
+// cancellations and new service alerts are sent to the user. It must only be
+// called from the scheduler goroutine.
+func (s *State) applyRealtime(now time.Time) {
+	feed := s.Realtime.Feed(now)
+	if feed == nil {
+		return
+	}
+	for tripID, scheduled := range s.scheduler.trips {
+		trip := scheduled.trip
+		if scheduled.departure.IsZero() || now.After(scheduled.departure) || scheduled.departure.Sub(now) > realtimeHorizon {
+			continue
+		}
+		meta, ok := s.Meta.Get(tripID)
+		if !ok || s.skipped(trip, meta.ServiceDate, now) {
+			continue
+		}
+		status, ok := feed.Status(trip.ExpectedItinerary, meta.ServiceDate, now)
+		if !ok {
+			continue
+		}
+
+		rt, ok := s.scheduler.realtime[tripID]
+		if !ok || rt.serviceDate != meta.ServiceDate {
+			rt = &realtimeState{serviceDate: meta.ServiceDate, alerts: make(map[string]bool)}
+			s.scheduler.realtime[tripID] = rt
+		}
+		notices := make([]string, 0)
+		if status.Canceled && !rt.canceled {
+			rt.canceled = true
+			notices = append(notices, "❌ A vehicle on your route is **cancelled**, plan another way with /route")
+		}
+		for _, alert := range status.Alerts {
+			key := alert.ID + alert.Header
+			if rt.alerts[key] {
+				continue
+			}
+			rt.alerts[key] = true
+			notices = append(notices, formatServiceAlert(alert))
+		}
+		if shift := status.Delay - rt.delay; shift >= time.Minute || shift <= -time.Minute {
+			slog.Info("Trip delay changed", "name", trip.Name, "delay", status.Delay)
+			rt.delay = status.Delay
+			s.scheduleTrip(trip, now)
+		}
+
+		if len(notices) == 0 || meta.Muted {
+			continue
+		}
+		s.SendRequest(Request{
+			Kind:        RequestTripChanged,
+			UserID:      trip.UserID,
+			TripID:      trip.ID,
+			Message:     fmt.Sprintf("⚠️ **Service Update:** **%s**", trip.Name),
+			Trip:        trip,
+			ServiceDate: meta.ServiceDate,
+			Changes:     notices,
+		})
+	}
+}
+
+// canceled reports whether the realtime feeds have cancelled the occurrence
+// of the trip on serviceDate.
+func (sc *scheduler) canceled(tripID string, serviceDate string) bool {
+	rt, ok := sc.realtime[tripID]
+	return ok && rt.serviceDate == serviceDate && rt.canceled
+}
+
+func formatServiceAlert(alert realtime.Alert) string {
+	if alert.Description == "" || alert.Description == alert.Header {
+		return fmt.Sprintf("📢 **%s**", alert.Header)
+	}
+	return fmt.Sprintf("📢 **%s**\n%s", alert.Header, alert.Description)
+}
+
+// formatDelay describes the realtime delay of a trip for the departure alerts.
+func formatDelay(delay time.Duration) string {
+	minutes := int(delay.Round(time.Minute).Minutes())
+	switch {
+	case minutes > 0:
+		return fmt.Sprintf(" (**%d** min late)", minutes)
+	case minutes < 0:
+		return fmt.Sprintf(" (**%d** min early)", -minutes)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,105 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/vincbro/pascal/blaise"
+)
+
+// DefaultRechecks routes a trip again half an hour and ten minutes before it
+// leaves.
+var DefaultRechecks = []blaise.Time{30 * 60, 10 * 60}
+
+// recheck routes the trip again and, if blaise now has a different answer,
+// stores the new itinerary, reschedules the trip and tells the user what
+// changed. The trip is read from the database as it may have been edited or
+// removed since it was scheduled.
+func (s *State) recheck(userID string, tripID string, meta TripMeta) {
+	trip, err := s.DB.GetTrip(userID, tripID)
+	if err != nil {
+		slog.Warn("failed to get trip to recheck", "trip", tripID, "error", err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+	itineraries, err := s.BClient.Routing(ctx, trip.FromID, trip.ToID, trip.Time, trip.Departure, routingAlternatives)
+	if err != nil {
+		slog.Warn("failed to recheck trip", "name", trip.Name, "error", err)
+		return
+	}
+	previous := trip.ExpectedItinerary
+	trip.ExpectedItinerary = blaise.ClosestItinerary(previous, itineraries)
+	changes := itineraryChanges(previous, trip.ExpectedItinerary)
+	if len(changes) == 0 {
+		slog.Debug("Trip unchanged", "name", trip.Name)
+		return
+	}
+
+	if err := s.DB.UpdateTrip(trip); err != nil {
+		slog.Error("error while updating trip", "name", trip.Name, "error", err)
+		return
+	}
+	s.ScheduleTrip(trip)
+	slog.Info("Trip changed", "name", trip.Name, "changes", changes)
+
+	if meta.Muted {
+		return
+	}
+	s.SendRequest(Request{
+		Kind:        RequestTripChanged,
+		UserID:      trip.UserID,
+		TripID:      trip.ID,
+		Message:     fmt.Sprintf("⚠️ **Trip Changed:** **%s** now leaves at **%s**", trip.Name, trip.ExpectedItinerary.DepartureTime.ToHMSString()),
+		Trip:        trip,
+		ServiceDate: meta.ServiceDate,
+		Changes:     changes,
+	})
+}
+
+// itineraryChanges describes how next differs from previous in the ways that
+// matter to someone about to leave: when, on what and with how many changes.
+func itineraryChanges(previous blaise.Itinerary, next blaise.Itinerary) []string {
+	changes := make([]string, 0, 4)
+	if shift := timeShift(previous.DepartureTime, next.DepartureTime); shift != "" {
+		changes = append(changes, fmt.Sprintf("🕒 Departs **%s** instead of ~~%s~~ (%s)",
+			next.DepartureTime.ToHMSString(), previous.DepartureTime.ToHMSString(), shift))
+	}
+	if shift := timeShift(previous.ArrivalTime, next.ArrivalTime); shift != "" {
+		changes = append(changes, fmt.Sprintf("🏁 Arrives **%s** instead of ~~%s~~ (%s)",
+			next.ArrivalTime.ToHMSString(), previous.ArrivalTime.ToHMSString(), shift))
+	}
+	if lines := next.Lines(); !slices.Equal(previous.Lines(), lines) {
+		changes = append(changes, fmt.Sprintf("🚏 Takes **%s** instead of ~~%s~~",
+			formatLines(lines), formatLines(previous.Lines())))
+	}
+	if transfers := next.Transfers(); transfers > previous.Transfers() {
+		changes = append(changes, fmt.Sprintf("🔄 **%d** transfers instead of %d", transfers, previous.Transfers()))
+	}
+	return changes
+}
+
+func formatLines(lines []string) string {
+	if len(lines) == 0 {
+		return "Walk"
+	}
+	return strings.Join(lines, " ➔ ")
+}
+
+// timeShift describes how much later or earlier next is, empty if it is
+// within a minute of previous.
+func timeShift(previous blaise.Time, next blaise.Time) string {
+	minutes := (int(next) - int(previous)) / 60
+	switch {
+	case minutes > 0:
+		return fmt.Sprintf("%d min later", minutes)
+	case minutes < 0:
+		return fmt.Sprintf("%d min earlier", -minutes)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,244 @@
+package state
+
+import (
+	"container/heap"
+	"time"
+
+	"github.com/vincbro/pascal/blaise"
+	"github.com/vincbro/pascal/database"
+)
+
+type entryKind int
+
+const (
+	// entryAlert is a pre-departure alert, index points into the trip's alerts
+	entryAlert entryKind = iota
+	// entryGuidance is an in-trip notification, index points into the trip's guidance
+	entryGuidance
+	// entrySnooze repeats the departure alert once a snooze is over
+	entrySnooze
+	// entryDeparture closes the live alert message of the trip
+	entryDeparture
+	// entryRecheck routes the trip again, index points into the rechecks
+	entryRecheck
+	// entryRollover fires once the trip has arrived. It schedules the next
+	// occurrence, which moves the trip onto a new service date.
+	entryRollover
+)
+
+type alertEntry struct {
+	at     time.Time
+	tripID string
+	gen    uint64
+	kind   entryKind
+	index  int
+}
+
+// alertQueue is a min-heap of alert entries ordered by fire time.
+type alertQueue []*alertEntry
+
+func (q alertQueue) Len() int { return len(q) }
+func (q alertQueue) Less(i, j int) bool {
+	// On ties the rollover goes last so it never overtakes its own trip's entries
+	if q[i].at.Equal(q[j].at) {
+		return q[i].kind < q[j].kind
+	}
+	return q[i].at.Before(q[j].at)
+}
+func (q alertQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *alertQueue) Push(x any) {
+	*q = append(*q, x.(*alertEntry))
+}
+
+func (q *alertQueue) Pop() any {
+	old := *q
+	n := len(old)
+	entry := old[n-1]
+	old[n-1] = nil
+	*q = old[:n-1]
+	return entry
+}
+
+type scheduledTrip struct {
+	trip     *database.Trip
+	alerts   []blaise.Time
+	guidance []guidanceEvent
+	gen      uint64
+	// day is the service day of the scheduled occurrence
+	day       time.Time
+	departure time.Time
+	// delay is the realtime delay departure and the later entries include
+	delay time.Duration
+	// alerts before snoozedUntil are not sent
+	snoozedUntil time.Time
+}
+
+type scheduleCommand struct {
+	tripID string
+	// nil removes the trip from the scheduler
+	trip *database.Trip
+	// reset drops the trip's alert state before scheduling
+	reset bool
+	// snoozeUntil holds back the alerts of the scheduled trip until then
+	snoozeUntil time.Time
+}
+
+// scheduler keeps the pending alerts of every trip in a time-ordered queue.
+// Entries are never removed from the middle of the queue, instead every
+// (re)schedule bumps the trip's generation and stale entries are dropped when
+// they reach the head.
+type scheduler struct {
+	queue alertQueue
+	trips map[string]*scheduledTrip
+	gen   uint64
+	// rechecks are the offsets before departure every trip is routed again at
+	rechecks []blaise.Time
+	// realtime is what the realtime feeds last said about each trip
+	realtime map[string]*realtimeState
+}
+
+func newScheduler() *scheduler {
+	return &scheduler{
+		queue:    make(alertQueue, 0),
+		trips:    make(map[string]*scheduledTrip),
+		realtime: make(map[string]*realtimeState),
+	}
+}
+
+// schedule queues the alerts and in-trip guidance of the next occurrence of
+// trip that has not yet arrived and returns its service day. Entries that
+// should already have fired are skipped.
+func (sc *scheduler) schedule(trip *database.Trip, alerts []blaise.Time, now time.Time) (time.Time, bool) {
+	previous := sc.trips[trip.ID]
+	sc.gen++
+	scheduled := &scheduledTrip{
+		trip:     trip,
+		alerts:   alerts,
+		guidance: guidance(trip.ExpectedItinerary),
+		gen:      sc.gen,
+	}
+	sc.trips[trip.ID] = scheduled
+
+	day, ok := nextServiceDay(trip, now)
+	if !ok {
+		return time.Time{}, false
+	}
+	scheduled.day = day
+	push := func(at time.Time, kind entryKind, index int) {
+		if at.Before(now) {
+			return
+		}
+		heap.Push(&sc.queue, &alertEntry{at: at, tripID: trip.ID, gen: sc.gen, kind: kind, index: index})
+	}
+
+	// A snooze outlives rescheduling, e.g. after a recheck, as long as it is
+	// for the same occurrence
+	if previous != nil && previous.day.Equal(day) && previous.snoozedUntil.After(now) {
+		scheduled.snoozedUntil = previous.snoozedUntil
+		push(previous.snoozedUntil, entrySnooze, 0)
+	}
+
+	itinerary := trip.ExpectedItinerary
+	// Everything after the first departure moves along with a realtime delay
+	if rt, ok := sc.realtime[trip.ID]; ok && rt.serviceDate == day.Format(serviceDateLayout) {
+		scheduled.delay = rt.delay
+	}
+	departure := serviceTime(day, itinerary.DepartureTime).Add(scheduled.delay)
+	scheduled.departure = departure
+	for i, alert := range alerts {
+		push(departure.Add(-time.Duration(alert)*time.Second), entryAlert, i)
+	}
+	push(departure, entryDeparture, 0)
+	for i, recheck := range sc.rechecks {
+		push(departure.Add(-time.Duration(recheck)*time.Second), entryRecheck, i)
+	}
+	for i, event := range scheduled.guidance {
+		push(serviceTime(day, event.at).Add(scheduled.delay), entryGuidance, i)
+	}
+	// The rollover must never be skipped, otherwise the trip is never rescheduled
+	heap.Push(&sc.queue, &alertEntry{at: serviceTime(day, itinerary.ArrivalTime).Add(scheduled.delay), tripID: trip.ID, gen: sc.gen, kind: entryRollover})
+	return day, true
+}
+
+// snooze holds back the alerts of the trip until at and queues a reminder
+// then. It reports false if the trip is not scheduled.
+func (sc *scheduler) snooze(tripID string, at time.Time) bool {
+	scheduled, ok := sc.trips[tripID]
+	if !ok || scheduled.departure.IsZero() {
+		return false
+	}
+	scheduled.snoozedUntil = at
+	heap.Push(&sc.queue, &alertEntry{at: at, tripID: tripID, gen: scheduled.gen, kind: entrySnooze})
+	return true
+}
+
+func (sc *scheduler) unschedule(tripID string) {
+	delete(sc.trips, tripID)
+	delete(sc.realtime, tripID)
+}
+
+func (sc *scheduler) valid(entry *alertEntry) bool {
+	st, ok := sc.trips[entry.tripID]
+	return ok && st.gen == entry.gen
+}
+
+// next returns the fire time of the earliest valid entry.
+func (sc *scheduler) next() (time.Time, bool) {
+	for sc.queue.Len() > 0 {
+		head := sc.queue[0]
+		if sc.valid(head) {
+			return head.at, true
+		}
+		heap.Pop(&sc.queue)
+	}
+	return time.Time{}, false
+}
+
+// due pops every valid entry that should have fired by now.
+func (sc *scheduler) due(now time.Time) []*alertEntry {
+	entries := make([]*alertEntry, 0)
+	for sc.queue.Len() > 0 && !sc.queue[0].at.After(now) {
+		entry := heap.Pop(&sc.queue).(*alertEntry)
+		if sc.valid(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+// serviceTime returns the wall clock time of t on the service day. GTFS times
+// count from noon minus 12h rather than midnight, which is what keeps them
+// right on the days the clocks change.
+func serviceTime(day time.Time, t blaise.Time) time.Time {
+	noon := time.Date(day.Year(), day.Month(), day.Day(), 12, 0, 0, 0, day.Location())
+	return noon.Add(time.Duration(t)*time.Second - 12*time.Hour)
+}
+
+// serviceDayHorizon is how many days ahead nextServiceDay looks, enough for a
+// trip that runs every few months.
+const serviceDayHorizon = 400
+
+// nextServiceDay finds the first day the trip runs on that has not yet
+// arrived by now, looking at most serviceDayHorizon days ahead. The search
+// starts yesterday since an occurrence past 24:00:00 can still be underway.
+func nextServiceDay(trip *database.Trip, now time.Time) (time.Time, bool) {
+	if trip.Date != "" {
+		day, err := time.ParseInLocation(database.DateLayout, trip.Date, now.Location())
+		if err != nil || !serviceTime(day, trip.ExpectedItinerary.ArrivalTime).After(now) {
+			return time.Time{}, false
+		}
+		return day, true
+	}
+
+	for i := -1; i < serviceDayHorizon; i++ {
+		day := time.Date(now.Year(), now.Month(), now.Day()+i, 0, 0, 0, 0, now.Location())
+		if !trip.RunsOn(day) {
+			continue
+		}
+		if serviceTime(day, trip.ExpectedItinerary.ArrivalTime).After(now) {
+			return day, true
+		}
+	}
+	return time.Time{}, false
+}
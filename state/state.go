@@ -2,55 +2,126 @@ package state
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/vincbro/pascal/blaise"
+	"github.com/vincbro/pascal/calendar"
 	"github.com/vincbro/pascal/database"
+	"github.com/vincbro/pascal/realtime"
+	"gorm.io/gorm"
 )
 
-var alerts = []blaise.Time{60 * 60, 30 * 60, 15 * 60, 10 * 60, 5 * 60, 4 * 60, 3 * 60, 2 * 60, 1 * 60}
-
 type State struct {
 	DB      *database.Database
 	BClient *blaise.Client
+	Meta    *MetaStore
+	Clock   Clock
+	// Calendar is the holidays weekly trips don't run on, it may be nil
+	Calendar *calendar.Calendar
+	// Rechecks is how long before departure a trip is routed again to catch
+	// changes made during the day, it must be set before Start
+	Rechecks []blaise.Time
+	// Realtime is polled for delays, cancellations and service alerts, it may
+	// be nil and must be set before Start
+	Realtime *realtime.Client
 
 	gtfsUrl string
 
-	handlers  []RequestHandler
-	requests  chan Request
-	kill      chan struct{}
-	wg        sync.WaitGroup
-	tripsMeta map[string]TripMeta
+	handlers []RequestHandler
+	requests chan Request
+	kill     chan struct{}
+	wg       sync.WaitGroup
+	// stopMu orders Go against Stop, so nothing is added to wg once Stop
+	// waits on it
+	stopMu  sync.Mutex
+	stopped bool
+
+	scheduler    *scheduler
+	schedule     chan scheduleCommand
+	realtimeTick chan struct{}
 }
 
+const serviceDateLayout = "2006-01-02"
+
+// routingAlternatives is how many itineraries to ask for when re-routing a
+// trip, the one closest to the stored itinerary is kept.
+const routingAlternatives = 5
+
+// TripMeta is the runtime alert state of a trip for its upcoming service date.
+// Every change is written through to the database so delivered alerts and
+// mutes survive restarts.
 type TripMeta struct {
+	ServiceDate  string
 	AlertHistory []bool
 	Muted        bool
-	Edited       bool
+	// MessageID is the live alert message of the occurrence, edited by every
+	// later alert
+	MessageID string
+}
+
+func newTripMeta(serviceDate string, alertCount int) TripMeta {
+	return TripMeta{
+		ServiceDate:  serviceDate,
+		AlertHistory: make([]bool, alertCount),
+		Muted:        false,
+	}
 }
 
 func NewState(db *database.Database, bClient *blaise.Client, gtfsUrl string) *State {
-	return &State{
+	s := &State{
 		DB:      db,
 		BClient: bClient,
+		Clock:   RealClock{},
+
+		Rechecks: DefaultRechecks,
 
 		gtfsUrl: gtfsUrl,
 
-		wg:        sync.WaitGroup{},
-		handlers:  make([]RequestHandler, 0),
-		requests:  make(chan Request, 128),
-		kill:      make(chan struct{}),
-		tripsMeta: make(map[string]TripMeta),
+		wg:       sync.WaitGroup{},
+		handlers: make([]RequestHandler, 0),
+		requests: make(chan Request, 128),
+		kill:     make(chan struct{}),
+
+		scheduler:    newScheduler(),
+		schedule:     make(chan scheduleCommand, 128),
+		realtimeTick: make(chan struct{}, 1),
 	}
+	s.Meta = NewMetaStore(s.saveMeta)
+	return s
 }
 
+type RequestKind int
+
+const (
+	RequestDepartSoon RequestKind = iota
+	RequestTransfer
+	RequestAlight
+	RequestArrival
+	// RequestDeparted closes the live alert message once the trip has left
+	RequestDeparted
+	// RequestTripChanged tells the user the route of an upcoming trip changed
+	RequestTripChanged
+)
+
 type Request struct {
+	Kind    RequestKind
 	UserID  string
 	TripID  string
 	Message string
+	// Trip is the trip as it was scheduled, it is not always stored in the database
+	Trip *database.Trip
+	// ServiceDate is the day of the occurrence the request is about
+	ServiceDate string
+	// MessageID is the live alert message of the occurrence, empty until the
+	// first live alert has been sent
+	MessageID string
+	// Changes lists what changed for a RequestTripChanged
+	Changes []string
 }
 
 type RequestHandler = func(s *State, request Request) error
@@ -67,63 +138,180 @@ func (s *State) SendRequest(request Request) {
 	}
 }
 
-func (s *State) MuteTrip(tripID string) {
-	meta, ok := s.tripsMeta[tripID]
-	if !ok {
-		meta = TripMeta{
-			AlertHistory: make([]bool, len(alerts)),
-			Muted:        true,
-			Edited:       true,
+// ScheduleTrip (re)computes the alerts of trip, replacing any alerts that are
+// already queued for it.
+func (s *State) ScheduleTrip(trip *database.Trip) {
+	select {
+	case s.schedule <- scheduleCommand{tripID: trip.ID, trip: trip}:
+	case <-s.kill:
+	}
+}
+
+// ResetTrip forgets every delivered alert and mute of the trip before
+// rescheduling it, used when the trip was edited.
+func (s *State) ResetTrip(trip *database.Trip) {
+	select {
+	case s.schedule <- scheduleCommand{tripID: trip.ID, trip: trip, reset: true}:
+	case <-s.kill:
+	}
+}
+
+// SnoozeTrip holds back the departure alerts of the trip for d and alerts
+// again once it is over. The snooze is not kept across restarts.
+func (s *State) SnoozeTrip(tripID string, d time.Duration) {
+	select {
+	case s.schedule <- scheduleCommand{tripID: tripID, snoozeUntil: s.Clock.Now().Add(d)}:
+	case <-s.kill:
+	}
+}
+
+// UnscheduleTrip drops every queued alert of the trip.
+func (s *State) UnscheduleTrip(tripID string) {
+	select {
+	case s.schedule <- scheduleCommand{tripID: tripID}:
+	case <-s.kill:
+	}
+}
+
+// ErrNotScheduled is returned when muting a trip that has no upcoming
+// occurrence, there is nothing to mute.
+var ErrNotScheduled = errors.New("trip has no upcoming alerts")
+
+// MuteTrip silences the trip for its upcoming service date.
+func (s *State) MuteTrip(tripID string) error {
+	return s.setMuted(tripID, true)
+}
+
+func (s *State) UnMuteTrip(tripID string) error {
+	return s.setMuted(tripID, false)
+}
+
+// setMuted only changes the meta the scheduler loaded for the upcoming
+// occurrence, which has the service date in the users zone and the full
+// alert history.
+func (s *State) setMuted(tripID string, muted bool) error {
+	scheduled := true
+	_, err := s.Meta.Update(tripID, func(meta *TripMeta, ok bool) bool {
+		scheduled = ok
+		if !ok || meta.Muted == muted {
+			return false
 		}
-	} else {
-		meta.Muted = true
-		meta.Edited = true
+		meta.Muted = muted
+		return true
+	})
+	if err != nil {
+		slog.Error("failed to save alert state", "trip", tripID, "error", err)
+		return err
+	}
+	if !scheduled {
+		return ErrNotScheduled
 	}
-	s.tripsMeta[tripID] = meta
+	return nil
 }
 
-func (s *State) UnMuteTrip(tripID string) {
-	meta, ok := s.tripsMeta[tripID]
-	if !ok {
-		meta = TripMeta{
-			AlertHistory: make([]bool, len(alerts)),
-			Muted:        false,
-			Edited:       true,
+// SetAlertMessage remembers the live alert message of the trip on serviceDate
+// so later alerts edit it instead of posting new ones. It does nothing if the
+// trip has moved on to another service date.
+func (s *State) SetAlertMessage(tripID string, serviceDate string, messageID string) {
+	_, err := s.Meta.Update(tripID, func(meta *TripMeta, ok bool) bool {
+		if !ok || meta.ServiceDate != serviceDate {
+			return false
 		}
-	} else {
-		meta.Muted = false
-		meta.Edited = true
+		meta.MessageID = messageID
+		return true
+	})
+	if err != nil {
+		slog.Error("failed to save alert state", "trip", tripID, "error", err)
+	}
+}
+
+// loadMeta returns the persisted alert state of the trip on serviceDate, or a
+// fresh one if nothing has been stored yet. The alert history is sized to
+// alertCount.
+func (s *State) loadMeta(tripID string, serviceDate string, alertCount int) TripMeta {
+	stored, err := s.DB.GetAlertState(tripID, serviceDate)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			slog.Error("failed to load alert state", "trip", tripID, "error", err)
+		}
+		return newTripMeta(serviceDate, alertCount)
+	}
+	meta := TripMeta{
+		ServiceDate:  stored.ServiceDate,
+		AlertHistory: stored.AlertHistory,
+		Muted:        stored.Muted,
+		MessageID:    stored.MessageID,
+	}
+	if len(meta.AlertHistory) != alertCount {
+		meta.AlertHistory = make([]bool, alertCount)
+	}
+	return meta
+}
+
+func (s *State) saveMeta(tripID string, meta TripMeta) error {
+	return s.DB.SaveAlertState(&database.AlertState{
+		TripID:       tripID,
+		ServiceDate:  meta.ServiceDate,
+		AlertHistory: meta.AlertHistory,
+		Muted:        meta.Muted,
+		MessageID:    meta.MessageID,
+	})
+}
+
+// RescheduleUser reschedules every trip of the user, used when a user level
+// setting that affects the alerts changes.
+func (s *State) RescheduleUser(userID string) error {
+	trips, err := s.DB.GetAllUsersTrips(userID)
+	if err != nil {
+		return err
+	}
+	for _, trip := range trips {
+		s.ScheduleTrip(trip)
 	}
-	s.tripsMeta[tripID] = meta
+	return nil
 }
 
+// UpdateAllTrips re-routes every trip. If blaise is down, or goes down while
+// updating, the error wraps blaise.ErrUnavailable and the update should be
+// retried once the client is healthy again.
 func (s *State) UpdateAllTrips() error {
+	if !s.BClient.Healthy() {
+		return fmt.Errorf("deferring trip update: %w", blaise.ErrCircuitOpen)
+	}
 	wg := sync.WaitGroup{}
 	trips, err := s.DB.GetAllTrips()
 	if err != nil {
 		return err
 	}
 
+	var unavailable atomic.Int32
 	for _, t := range trips {
 		wg.Add(1)
 		go func(trip *database.Trip) {
 			defer wg.Done()
 			ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
-			itinerary, err := s.BClient.Routing(ctx, trip.FromID, trip.ToID, trip.Time, trip.Departure)
+			itineraries, err := s.BClient.Routing(ctx, trip.FromID, trip.ToID, trip.Time, trip.Departure, routingAlternatives)
 			cancel()
 			if err != nil {
+				if errors.Is(err, blaise.ErrUnavailable) || errors.Is(err, blaise.ErrDataLoading) {
+					unavailable.Add(1)
+				}
 				slog.Error("error while getting trip", "name", trip.Name, "error", err)
 				return
 			}
-			trip.ExpectedItinerary = itinerary
+			trip.ExpectedItinerary = blaise.ClosestItinerary(trip.ExpectedItinerary, itineraries)
 			if err := s.DB.UpdateTrip(trip); err != nil {
 				slog.Error("error while updating trip", "name", trip.Name, "error", err)
 				return
 			}
+			s.ScheduleTrip(trip)
 			slog.Info("Updated trip", "name", trip.Name)
 		}(t)
 	}
 	wg.Wait()
+	if n := unavailable.Load(); n > 0 {
+		return fmt.Errorf("%d trips left stale: %w", n, blaise.ErrUnavailable)
+	}
 	return nil
 }
 
@@ -145,89 +333,43 @@ func (s *State) Start() {
 	})
 
 	// Send notifications
-	s.wg.Go(func() {
-		ticker := time.NewTicker(30 * time.Second)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ticker.C:
-				now := time.Now()
-				midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
-				currentSeconds := blaise.Time(now.Sub(midnight).Seconds())
-
-				trips, err := s.DB.GetAllTrips()
-				if err != nil {
-					slog.Error("failed to fetch trips", "error", err)
-					break
-				}
-				for _, trip := range trips {
-					if !trip.ShouldRun(now.Weekday()) {
-						continue
-					}
-					meta, ok := s.tripsMeta[trip.ID]
-					// Create a new if we don't have one
-					if !ok {
-						meta = TripMeta{
-							AlertHistory: make([]bool, len(alerts)),
-							Muted:        false,
-							Edited:       false,
-						}
-					}
-
-					departure := trip.ExpectedItinerary.DepartureTime
-					if currentSeconds > departure {
-						if meta.Edited {
-							for i := range meta.AlertHistory {
-								meta.AlertHistory[i] = false
-							}
-							meta.Muted = false
-							meta.Edited = false
-							slog.Debug("Reset", "name", trip.Name, "meta", meta)
-						}
-						s.tripsMeta[trip.ID] = meta
-						continue
-					}
-
-					if meta.Muted {
-						slog.Debug("Was Muted", "name", trip.Name, "meta", meta)
-						continue
-					}
+	s.wg.Go(s.runScheduler)
+	if s.Realtime != nil {
+		s.wg.Go(s.pollRealtime)
+	}
 
-					diff := departure - currentSeconds
-					shouldNotify := false
-					minsLeft := blaise.Time(0)
-					for i, alert := range alerts {
-						if alert > diff && !meta.AlertHistory[i] {
-							meta.AlertHistory[i] = true
-							meta.Edited = true
-							shouldNotify = true
-							minsLeft = alert / 60
-						}
-					}
-					if shouldNotify {
-						slog.Debug("Updated", "name", trip.Name, "meta", meta)
-						s.tripsMeta[trip.ID] = meta
-						s.SendRequest(Request{
-							UserID:  trip.UserID,
-							TripID:  trip.ID,
-							Message: fmt.Sprintf("🔔 **Depart Soon:** **%s** leaves in **%d** min!", trip.Name, minsLeft)})
-					}
-				}
-			case <-s.kill:
-				return
+	// Update data
+	s.wg.Go(func() {
+		pending := false
+		update := func() {
+			err := s.UpdateAllTrips()
+			pending = errors.Is(err, blaise.ErrUnavailable)
+			if pending {
+				slog.Warn("blaise is unhealthy, retrying trip update later", "circuit", s.BClient.CircuitState(), "error", err)
+			} else if err != nil {
+				slog.Error("error while updating trips", "error", err)
 			}
 		}
-	})
 
-	// Update data
-	s.wg.Go(func() {
-		ticker := time.NewTicker(1 * time.Hour)
-		defer ticker.Stop()
+		hourly := s.Clock.NewTimer(1 * time.Hour)
+		retry := s.Clock.NewTimer(1 * time.Minute)
+		defer hourly.Stop()
+		defer retry.Stop()
 		for {
+			if pending {
+				retry.Reset(1 * time.Minute)
+			} else {
+				retry.Stop()
+			}
+
 			select {
-			case <-ticker.C:
-				now := time.Now()
+			case <-retry.C():
+				if s.BClient.Healthy() {
+					update()
+				}
+			case <-hourly.C():
+				hourly.Reset(1 * time.Hour)
+				now := s.Clock.Now()
 				if now.Hour() >= 6 && now.Hour() < 8 {
 					ageSeconds, err := s.BClient.GetAge(context.Background())
 					if err != nil {
@@ -244,7 +386,7 @@ func (s *State) Start() {
 							slog.Error("error while trying to trigger refresh", "error", err)
 						} else {
 							slog.Info("GTFS refreshed successfully")
-							s.UpdateAllTrips()
+							update()
 						}
 					}
 				}
@@ -254,8 +396,276 @@ func (s *State) Start() {
 		}
 	})
 }
+
+// housekeeping drops alert states and exceptions that are over and resumes
+// trips whose pause has ended. It runs at startup and every midnight.
+func (s *State) housekeeping(now time.Time) {
+	yesterday := now.AddDate(0, 0, -1).Format(serviceDateLayout)
+	if err := s.DB.PruneAlertStates(yesterday); err != nil {
+		slog.Error("failed to prune alert states", "error", err)
+	}
+	if err := s.DB.PruneExceptions(yesterday); err != nil {
+		slog.Error("failed to prune exceptions", "error", err)
+	}
+	if err := s.DB.ResumeTrips(now.Format(serviceDateLayout)); err != nil {
+		slog.Error("failed to resume trips", "error", err)
+	}
+}
+
+// untilMidnight is how long it is from now until the next day starts.
+func untilMidnight(now time.Time) time.Duration {
+	return time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location()).Sub(now)
+}
+
+func (s *State) runScheduler() {
+	s.scheduler.rechecks = s.Rechecks
+	now := s.Clock.Now()
+	s.housekeeping(now)
+	trips, err := s.DB.GetAllTrips()
+	if err != nil {
+		slog.Error("failed to fetch trips", "error", err)
+	}
+	for _, trip := range trips {
+		s.scheduleTrip(trip, now)
+	}
+	slog.Info("Scheduled trips", "count", len(trips))
+
+	// A single timer follows the head of the queue, every schedule command
+	// moves it
+	wake := s.Clock.NewTimer(0)
+	wake.Stop()
+	defer wake.Stop()
+	midnight := s.Clock.NewTimer(untilMidnight(now))
+	defer midnight.Stop()
+	for {
+		if at, ok := s.scheduler.next(); ok {
+			wake.Reset(at.Sub(s.Clock.Now()))
+		} else {
+			wake.Stop()
+		}
+
+		select {
+		case <-wake.C():
+			now := s.Clock.Now()
+			for _, entry := range s.scheduler.due(now) {
+				s.fire(entry, now)
+			}
+		case <-midnight.C():
+			now := s.Clock.Now()
+			s.housekeeping(now)
+			midnight.Reset(untilMidnight(now))
+		case <-s.realtimeTick:
+			s.applyRealtime(s.Clock.Now())
+		case cmd := <-s.schedule:
+			if !cmd.snoozeUntil.IsZero() {
+				if !s.scheduler.snooze(cmd.tripID, cmd.snoozeUntil) {
+					slog.Warn("Can't snooze a trip that isn't scheduled", "trip", cmd.tripID)
+				}
+				continue
+			}
+			if cmd.reset {
+				if err := s.DB.RemoveAlertStates(cmd.tripID); err != nil {
+					slog.Error("failed to remove alert states", "trip", cmd.tripID, "error", err)
+				}
+				s.Meta.Delete(cmd.tripID)
+			}
+			if cmd.trip == nil {
+				s.scheduler.unschedule(cmd.tripID)
+				s.Meta.Delete(cmd.tripID)
+			} else {
+				s.scheduleTrip(cmd.trip, s.Clock.Now())
+			}
+		case <-s.kill:
+			return
+		}
+	}
+}
+
+// scheduleTrip queues the next occurrence of trip and makes sure its meta
+// belongs to that occurrence's service date.
+func (s *State) scheduleTrip(trip *database.Trip, now time.Time) {
+	user, err := s.DB.GetUser(trip.UserID)
+	if err != nil {
+		slog.Error("failed to get user of trip", "name", trip.Name, "error", err)
+	}
+	alerts := trip.AlertOffsets(user)
+	// Service days follow the calendar of the user, not the server
+	day, ok := s.scheduler.schedule(trip, alerts, now.In(user.Location()))
+	if !ok {
+		// A dated trip without an upcoming occurrence has already happened
+		if trip.Date != "" {
+			s.expireTrip(trip)
+		}
+		return
+	}
+	// The service date is the day the occurrence belongs to, which is not the
+	// day it departs on for trips past midnight
+	serviceDate := day.Format(serviceDateLayout)
+	if meta, ok := s.Meta.Get(trip.ID); !ok || meta.ServiceDate != serviceDate || len(meta.AlertHistory) != len(alerts) {
+		s.Meta.Put(trip.ID, s.loadMeta(trip.ID, serviceDate, len(alerts)))
+	}
+}
+
+// HasDeparted reports whether a trip that runs once has already left, its
+// alerts would never be sent. Repeating trips always have a next occurrence.
+func (s *State) HasDeparted(trip *database.Trip, user *database.User) bool {
+	if trip.Date == "" {
+		return false
+	}
+	now := s.Clock.Now().In(user.Location())
+	day, err := time.ParseInLocation(database.DateLayout, trip.Date, now.Location())
+	if err != nil {
+		return false
+	}
+	return !serviceTime(day, trip.ExpectedItinerary.DepartureTime).After(now)
+}
+
+// expireTrip removes a dated trip that has completed.
+func (s *State) expireTrip(trip *database.Trip) {
+	s.scheduler.unschedule(trip.ID)
+	s.Meta.Delete(trip.ID)
+	if err := s.DB.RemoveTrip(trip.UserID, trip.ID); err != nil {
+		slog.Error("failed to remove completed trip", "name", trip.Name, "error", err)
+		return
+	}
+	slog.Info("Removed completed trip", "name", trip.Name, "date", trip.Date)
+}
+
+func (s *State) fire(entry *alertEntry, now time.Time) {
+	scheduled := s.scheduler.trips[entry.tripID]
+	trip := scheduled.trip
+
+	if entry.kind == entryRollover {
+		s.scheduleTrip(trip, now)
+		return
+	}
+	// Holidays, away ranges and skip dates can change at any time so they are
+	// looked up when the entry is due
+	if meta, ok := s.Meta.Get(trip.ID); ok && s.skipped(trip, meta.ServiceDate, now) {
+		return
+	}
+
+	var request Request
+	switch entry.kind {
+	case entryGuidance:
+		meta, _ := s.Meta.Get(trip.ID)
+		if meta.Muted {
+			slog.Debug("Was Muted", "name", trip.Name, "meta", meta)
+			return
+		}
+		event := scheduled.guidance[entry.index]
+		request = Request{
+			Kind:        event.requestKind(),
+			UserID:      trip.UserID,
+			TripID:      trip.ID,
+			Message:     event.message(trip.Name, trip.ExpectedItinerary),
+			Trip:        trip,
+			ServiceDate: meta.ServiceDate,
+		}
+	case entryRecheck:
+		meta, _ := s.Meta.Get(trip.ID)
+		// Routing can take a while, the scheduler must not wait for it
+		s.wg.Go(func() {
+			s.recheck(trip.UserID, trip.ID, meta)
+		})
+		return
+	case entryDeparture:
+		// Only live alerts have anything to close
+		meta, _ := s.Meta.Get(trip.ID)
+		if meta.MessageID == "" {
+			return
+		}
+		request = Request{
+			Kind:        RequestDeparted,
+			UserID:      trip.UserID,
+			TripID:      trip.ID,
+			Message:     fmt.Sprintf("🚆 **Departed:** **%s** left at **%s**", trip.Name, trip.ExpectedItinerary.DepartureTime.ToHMSString()),
+			Trip:        trip,
+			ServiceDate: meta.ServiceDate,
+			MessageID:   meta.MessageID,
+		}
+	case entrySnooze:
+		meta, _ := s.Meta.Get(trip.ID)
+		if meta.Muted {
+			return
+		}
+		message := fmt.Sprintf("⏰ **Snoozed:** **%s** is leaving now!", trip.Name)
+		if minutes := int(scheduled.departure.Sub(now).Minutes()); minutes > 0 {
+			message = fmt.Sprintf("⏰ **Snoozed:** **%s** leaves in **%d** min!", trip.Name, minutes)
+		}
+		request = Request{
+			Kind:        RequestDepartSoon,
+			UserID:      trip.UserID,
+			TripID:      trip.ID,
+			Message:     message,
+			Trip:        trip,
+			ServiceDate: meta.ServiceDate,
+			MessageID:   meta.MessageID,
+		}
+	case entryAlert:
+		notify := false
+		snoozed := now.Before(scheduled.snoozedUntil)
+		// The store persists before returning so a restart can never deliver the
+		// same alert twice
+		meta, err := s.Meta.Update(trip.ID, func(meta *TripMeta, ok bool) bool {
+			if !ok || meta.Muted {
+				return false
+			}
+			if s.scheduler.canceled(trip.ID, meta.ServiceDate) {
+				return false
+			}
+			if len(meta.AlertHistory) != len(scheduled.alerts) {
+				meta.AlertHistory = make([]bool, len(scheduled.alerts))
+			}
+			if meta.AlertHistory[entry.index] {
+				return false
+			}
+			// A snoozed alert counts as delivered, the snooze sends its own
+			meta.AlertHistory[entry.index] = true
+			notify = !snoozed
+			return true
+		})
+		if err != nil {
+			slog.Error("failed to save alert state, skipping alert", "name", trip.Name, "error", err)
+			return
+		}
+		if !notify {
+			slog.Debug("Skipped", "name", trip.Name, "meta", meta)
+			return
+		}
+		slog.Debug("Updated", "name", trip.Name, "meta", meta)
+		request = Request{
+			Kind:        RequestDepartSoon,
+			UserID:      trip.UserID,
+			TripID:      trip.ID,
+			Message:     fmt.Sprintf("🔔 **Depart Soon:** **%s** leaves in **%d** min!%s", trip.Name, scheduled.alerts[entry.index]/60, formatDelay(scheduled.delay)),
+			Trip:        trip,
+			ServiceDate: meta.ServiceDate,
+			MessageID:   meta.MessageID,
+		}
+	}
+	s.SendRequest(request)
+}
+
+// Go runs f in the background as part of the state, so Stop waits for it.
+// done is closed when the state stops and f must return soon after. Nothing
+// is run once the state has stopped.
+func (s *State) Go(f func(done <-chan struct{})) {
+	s.stopMu.Lock()
+	defer s.stopMu.Unlock()
+	if s.stopped {
+		return
+	}
+	s.wg.Go(func() {
+		f(s.kill)
+	})
+}
+
 func (s *State) Stop() {
+	s.stopMu.Lock()
+	s.stopped = true
 	close(s.kill)
+	s.stopMu.Unlock()
 	s.wg.Wait()
 	slog.Info("Stopped trip watcher")
 }
@@ -0,0 +1,257 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/vincbro/pascal/blaise"
+	"github.com/vincbro/pascal/blaise/blaisetest"
+	"github.com/vincbro/pascal/database"
+	"github.com/vincbro/pascal/state/statetest"
+)
+
+// The fixture commute: Odenplan ➔ Tekniska högskolan, leaving 08:00 on the
+// 19 to Centralen and changing to the 14.
+const (
+	odenplan  = "740021666"
+	tekniska  = "740020749"
+	stockholm = statetest.Zone
+)
+
+// schedulerTimers is how many timers the scheduler keeps armed, the head of
+// the queue and midnight.
+const schedulerTimers = 2
+
+type testEnv struct {
+	state  *State
+	clock  *FakeClock
+	server *blaisetest.Server
+	user   *database.User
+}
+
+// newTestEnv builds a State on a statetest env with a fake clock set to now.
+// Nothing runs until the test starts it.
+func newTestEnv(t *testing.T, now time.Time) *testEnv {
+	t.Helper()
+	env := statetest.New(t)
+	clock := NewFakeClock(now)
+	s := NewState(env.DB, env.Server.Client(), "")
+	s.Clock = clock
+	s.Rechecks = nil
+	return &testEnv{state: s, clock: clock, server: env.Server, user: env.User}
+}
+
+// addCommute saves the daily fixture commute with the given alerts.
+func (e *testEnv) addCommute(t *testing.T, id string, alerts ...blaise.Time) *database.Trip {
+	t.Helper()
+	itineraries, err := e.state.BClient.Routing(context.Background(), odenplan, tekniska, "08:00:00", true, 1)
+	if err != nil {
+		t.Fatalf("failed to route commute: %v", err)
+	}
+	trip := &database.Trip{
+		ID:                id,
+		UserID:            e.user.ID,
+		Name:              "Work",
+		From:              itineraries[0].From.Name,
+		FromID:            odenplan,
+		To:                itineraries[0].To.Name,
+		ToID:              tekniska,
+		Time:              "08:00:00",
+		Departure:         true,
+		Recurrence:        database.Recurrence{Freq: database.Daily, Interval: 1},
+		Alerts:            alerts,
+		ExpectedItinerary: itineraries[0],
+	}
+	if err := e.state.DB.AddTrip(trip); err != nil {
+		t.Fatalf("failed to add trip: %v", err)
+	}
+	return trip
+}
+
+// runScheduler starts the scheduler alone, requests are read straight off the
+// channel so their order is exactly the order they were sent in.
+func (e *testEnv) runScheduler(t *testing.T) {
+	t.Helper()
+	e.state.wg.Go(e.state.runScheduler)
+	t.Cleanup(e.state.Stop)
+	e.clock.BlockUntil(schedulerTimers)
+}
+
+type sentRequest struct {
+	at   string
+	kind RequestKind
+}
+
+// step advances the clock, waits for the scheduler to block again and
+// returns everything it sent in between.
+func (e *testEnv) step(d time.Duration) []Request {
+	e.clock.Advance(d)
+	e.clock.BlockUntil(schedulerTimers)
+	requests := make([]Request, 0)
+	for {
+		select {
+		case request := <-e.state.requests:
+			requests = append(requests, request)
+		default:
+			return requests
+		}
+	}
+}
+
+func mustLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("failed to load %s: %v", name, err)
+	}
+	return loc
+}
+
+func TestCommuterDay(t *testing.T) {
+	loc := mustLocation(t, stockholm)
+	env := newTestEnv(t, time.Date(2026, 10, 19, 6, 0, 0, 0, loc))
+	trip := env.addCommute(t, "commute", 15*60, 5*60)
+	env.runScheduler(t)
+
+	got := make([]sentRequest, 0)
+	for range 3 * 60 {
+		for _, request := range env.step(time.Minute) {
+			if request.TripID != trip.ID || request.ServiceDate != "2026-10-19" {
+				t.Errorf("request for %s on %s, want %s on 2026-10-19", request.TripID, request.ServiceDate, trip.ID)
+			}
+			got = append(got, sentRequest{at: env.clock.Now().Format("15:04"), kind: request.Kind})
+		}
+		// Rescheduling must reuse the scheduler's timers, not pile up new ones
+		if waiters := env.clock.Waiters(); waiters != schedulerTimers {
+			t.Fatalf("%d timers armed at %s, want %d", waiters, env.clock.Now().Format("15:04"), schedulerTimers)
+		}
+		if env.clock.Now().Hour() == 7 && env.clock.Now().Minute() == 0 {
+			for range 20 {
+				env.state.ScheduleTrip(trip)
+			}
+		}
+	}
+
+	want := []sentRequest{
+		{"07:45", RequestDepartSoon},
+		{"07:55", RequestDepartSoon},
+		{"08:02", RequestTransfer},
+		{"08:09", RequestAlight},
+		{"08:13", RequestArrival},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got requests %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("request %d is %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	// The day is over, tomorrow's first alert is next
+	meta, ok := env.state.Meta.Get(trip.ID)
+	if !ok || meta.ServiceDate != "2026-10-20" {
+		t.Errorf("meta is for %q after arrival, want 2026-10-20", meta.ServiceDate)
+	}
+}
+
+func TestMuteTrip(t *testing.T) {
+	env := newTestEnv(t, time.Date(2026, 10, 19, 7, 30, 0, 0, time.UTC))
+	trip := env.addCommute(t, "commute", 15*60, 5*60)
+
+	// Nothing is loaded before the scheduler runs, there is nothing to mute
+	if err := env.state.MuteTrip(trip.ID); !errors.Is(err, ErrNotScheduled) {
+		t.Errorf("muting an unscheduled trip gave %v, want ErrNotScheduled", err)
+	}
+	if _, err := env.state.DB.GetAlertState(trip.ID, "2026-10-19"); err == nil {
+		t.Error("muting an unscheduled trip stored an alert state")
+	}
+
+	// 07:30 UTC is 09:30 in Stockholm, the commute has left for today
+	env.runScheduler(t)
+	if err := env.state.MuteTrip(trip.ID); err != nil {
+		t.Fatalf("failed to mute: %v", err)
+	}
+	meta, _ := env.state.Meta.Get(trip.ID)
+	if !meta.Muted || meta.ServiceDate != "2026-10-20" || len(meta.AlertHistory) != 2 {
+		t.Errorf("muted meta is %+v, want tomorrow with two alerts", meta)
+	}
+	stored, err := env.state.DB.GetAlertState(trip.ID, "2026-10-20")
+	if err != nil || !stored.Muted || len(stored.AlertHistory) != 2 {
+		t.Errorf("stored alert state is %+v, %v", stored, err)
+	}
+
+	// Tomorrow's alerts are held back until unmuted
+	env.clock.Set(time.Date(2026, 10, 20, 5, 44, 0, 0, time.UTC))
+	env.clock.BlockUntil(schedulerTimers)
+	if requests := env.step(2 * time.Minute); len(requests) != 0 {
+		t.Errorf("a muted trip sent %v", requests)
+	}
+	if err := env.state.UnMuteTrip(trip.ID); err != nil {
+		t.Fatalf("failed to unmute: %v", err)
+	}
+	if requests := env.step(10 * time.Minute); len(requests) != 1 || requests[0].Kind != RequestDepartSoon {
+		t.Errorf("an unmuted trip sent %v, want its next alert", requests)
+	}
+}
+
+func TestSnoozeSurvivesReschedule(t *testing.T) {
+	loc := mustLocation(t, stockholm)
+	env := newTestEnv(t, time.Date(2026, 10, 19, 7, 40, 0, 0, loc))
+	trip := env.addCommute(t, "commute", 15*60, 5*60)
+	env.runScheduler(t)
+
+	env.state.SnoozeTrip(trip.ID, 10*time.Minute)
+	// A recheck or trip update reschedules the same occurrence
+	env.state.ScheduleTrip(trip)
+
+	got := make([]string, 0)
+	for range 16 {
+		for _, request := range env.step(time.Minute) {
+			if request.Kind == RequestDepartSoon {
+				got = append(got, env.clock.Now().In(loc).Format("15:04"))
+			}
+		}
+	}
+	// The 07:45 alert stays held back, the snooze reminds at 07:50
+	if want := []string{"07:50", "07:55"}; !slices.Equal(got, want) {
+		t.Errorf("got depart soon alerts at %v, want %v", got, want)
+	}
+}
+
+func TestMidnightHousekeeping(t *testing.T) {
+	loc := mustLocation(t, stockholm)
+	env := newTestEnv(t, time.Date(2026, 10, 19, 23, 0, 0, 0, loc))
+	trip := env.addCommute(t, "commute", 15*60)
+	trip.Paused, trip.PausedUntil = true, "2026-10-19"
+	if err := env.state.DB.UpdateTrip(trip); err != nil {
+		t.Fatalf("failed to pause trip: %v", err)
+	}
+	env.runScheduler(t)
+
+	// Still current when the scheduler started
+	if err := env.state.DB.SaveAlertState(&database.AlertState{TripID: trip.ID, ServiceDate: "2026-10-18"}); err != nil {
+		t.Fatalf("failed to save alert state: %v", err)
+	}
+	if err := env.state.DB.AddSkipDate(&database.SkipDate{TripID: trip.ID, Date: "2026-10-18"}); err != nil {
+		t.Fatalf("failed to add skip date: %v", err)
+	}
+
+	env.step(time.Hour)
+
+	if _, err := env.state.DB.GetAlertState(trip.ID, "2026-10-18"); err == nil {
+		t.Error("the alert state of two days ago is still stored")
+	}
+	if skips, _ := env.state.DB.GetSkipDates(trip.ID); len(skips) != 0 {
+		t.Errorf("skip dates %v are still stored", skips)
+	}
+	if trip, _ := env.state.DB.GetTrip(env.user.ID, trip.ID); trip.Paused {
+		t.Error("the trip is still paused after its pause ended")
+	}
+	if waiters := env.clock.Waiters(); waiters != schedulerTimers {
+		t.Errorf("%d timers armed after midnight, want %d", waiters, schedulerTimers)
+	}
+}
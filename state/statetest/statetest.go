@@ -0,0 +1,43 @@
+// Package statetest sets up what a State under test runs on, so the tests of
+// every package start from the same world.
+package statetest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/vincbro/pascal/blaise/blaisetest"
+	"github.com/vincbro/pascal/database"
+)
+
+// Zone is the time zone of the test user.
+const Zone = "Europe/Stockholm"
+
+// Env is a fresh database holding one user living in Zone and a fake blaise
+// server loaded with the bundled fixtures. Both are closed with the test.
+type Env struct {
+	DB     *database.Database
+	Server *blaisetest.Server
+	User   *database.User
+}
+
+// New builds an Env for t.
+func New(t testing.TB) *Env {
+	t.Helper()
+	db, err := database.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	server := blaisetest.NewServer()
+	t.Cleanup(server.Close)
+
+	user, err := db.GetOrCreateUser("user", "commuter")
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	user.TimeZone = Zone
+	if err := db.UpdateUser(user); err != nil {
+		t.Fatalf("failed to update user: %v", err)
+	}
+	return &Env{DB: db, Server: server, User: user}
+}
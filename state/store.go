@@ -0,0 +1,83 @@
+package state
+
+import (
+	"maps"
+	"slices"
+	"sync"
+)
+
+// MetaStore is a concurrency-safe store of the runtime state of every trip.
+// It is shared between the scheduler and the Discord event goroutines, so all
+// access goes through it and callers only ever see copies.
+type MetaStore struct {
+	mu      sync.RWMutex
+	metas   map[string]TripMeta
+	persist func(tripID string, meta TripMeta) error
+}
+
+// NewMetaStore creates an empty store. persist is called, while holding the
+// store lock, for every change made through Update so writes reach the
+// database in the same order as they were applied.
+func NewMetaStore(persist func(tripID string, meta TripMeta) error) *MetaStore {
+	return &MetaStore{
+		metas:   make(map[string]TripMeta),
+		persist: persist,
+	}
+}
+
+func (m TripMeta) clone() TripMeta {
+	m.AlertHistory = slices.Clone(m.AlertHistory)
+	return m
+}
+
+func (ms *MetaStore) Get(tripID string) (TripMeta, bool) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	meta, ok := ms.metas[tripID]
+	return meta.clone(), ok
+}
+
+// Put replaces the meta of a trip without persisting it, used for state that
+// was just loaded from the database.
+func (ms *MetaStore) Put(tripID string, meta TripMeta) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.metas[tripID] = meta.clone()
+}
+
+func (ms *MetaStore) Delete(tripID string) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	delete(ms.metas, tripID)
+}
+
+// Update atomically applies fn to the meta of a trip. fn gets a zero meta and
+// ok set to false if the trip has none yet, and returns whether it changed
+// anything. Changes are only kept if they could be persisted.
+func (ms *MetaStore) Update(tripID string, fn func(meta *TripMeta, ok bool) bool) (TripMeta, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	current, ok := ms.metas[tripID]
+	meta := current.clone()
+	if !fn(&meta, ok) {
+		return current.clone(), nil
+	}
+	if ms.persist != nil {
+		if err := ms.persist(tripID, meta); err != nil {
+			return current.clone(), err
+		}
+	}
+	ms.metas[tripID] = meta
+	return meta.clone(), nil
+}
+
+// Snapshot returns a copy of the meta of every trip.
+func (ms *MetaStore) Snapshot() map[string]TripMeta {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	snapshot := maps.Clone(ms.metas)
+	for id, meta := range snapshot {
+		snapshot[id] = meta.clone()
+	}
+	return snapshot
+}
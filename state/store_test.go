@@ -0,0 +1,118 @@
+package state
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/vincbro/pascal/database"
+)
+
+func TestMetaStoreCopies(t *testing.T) {
+	store := NewMetaStore(nil)
+	store.Put("trip", newTripMeta("2026-10-19", 2))
+
+	meta, _ := store.Get("trip")
+	meta.AlertHistory[0] = true
+	snapshot := store.Snapshot()
+	snapshot["trip"].AlertHistory[1] = true
+
+	if meta, _ := store.Get("trip"); meta.AlertHistory[0] || meta.AlertHistory[1] {
+		t.Errorf("changing a copy changed the store: %v", meta.AlertHistory)
+	}
+}
+
+// TestConcurrentAccess is meant for go test -race, Discord events muting and
+// reading trips while the scheduler fires their alerts.
+func TestConcurrentAccess(t *testing.T) {
+	env := newTestEnv(t, time.Date(2026, 10, 19, 7, 0, 0, 0, mustLocation(t, stockholm)))
+	trips := make([]*database.Trip, 0, 4)
+	for _, id := range []string{"a", "b", "c", "d"} {
+		trips = append(trips, env.addCommute(t, id, database.DefaultAlerts...))
+	}
+	env.runScheduler(t)
+
+	done := make(chan struct{})
+	wg := sync.WaitGroup{}
+	for i, trip := range trips {
+		wg.Go(func() {
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if i%2 == 0 {
+					env.state.MuteTrip(trip.ID)
+				} else {
+					env.state.UnMuteTrip(trip.ID)
+				}
+				if meta, ok := env.state.Meta.Get(trip.ID); ok {
+					meta.AlertHistory = append(meta.AlertHistory, true)
+				}
+			}
+		})
+	}
+	wg.Go(func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			for _, meta := range env.state.Meta.Snapshot() {
+				for i := range meta.AlertHistory {
+					meta.AlertHistory[i] = false
+				}
+			}
+			env.state.Meta.Delete(trips[3].ID)
+		}
+	})
+
+	// Every alert of the countdown fires while the goroutines above run
+	for range 90 {
+		env.step(time.Minute)
+	}
+	close(done)
+	wg.Wait()
+
+	for _, trip := range trips[:3] {
+		if _, ok := env.state.Meta.Get(trip.ID); !ok {
+			t.Errorf("trip %s lost its meta", trip.ID)
+		}
+	}
+}
+
+// TestGoWhileStopping keeps starting background work while the state stops.
+// Stop must wait for everything it let start and nothing may start after it.
+func TestGoWhileStopping(t *testing.T) {
+	for range 50 {
+		s := NewState(nil, nil, "")
+		var started, finished atomic.Int32
+		var callers sync.WaitGroup
+		for range 8 {
+			callers.Go(func() {
+				for range 200 {
+					s.Go(func(done <-chan struct{}) {
+						started.Add(1)
+						finished.Add(1)
+					})
+				}
+			})
+		}
+		for started.Load() < 100 {
+			runtime.Gosched()
+		}
+		s.Stop()
+		stoppedAt := started.Load()
+		if finished.Load() != stoppedAt {
+			t.Fatalf("%d started but %d finished when Stop returned", stoppedAt, finished.Load())
+		}
+		callers.Wait()
+		if started.Load() != stoppedAt {
+			t.Fatalf("%d started after Stop returned", started.Load()-stoppedAt)
+		}
+	}
+}
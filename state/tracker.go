@@ -0,0 +1,118 @@
+package state
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vincbro/pascal/blaise"
+)
+
+// guidanceLead is how long before a transfer or the final stop the traveller
+// is told to get ready to get off, when blaise didn't list the stops of the
+// leg.
+const guidanceLead blaise.Time = 60
+
+type guidanceKind int
+
+const (
+	guidanceTransfer guidanceKind = iota
+	guidanceAlight
+	guidanceArrival
+)
+
+// guidanceEvent is a notification sent while a trip is underway.
+type guidanceEvent struct {
+	kind guidanceKind
+	at   blaise.Time
+	// leg is the leg being left, next is the leg to change to on a transfer
+	leg  int
+	next int
+}
+
+// guidance walks the legs of the itinerary and returns the in-trip
+// notifications in the order they should be sent: one before getting off each
+// vehicle, naming the next one on transfers, and one on arrival.
+func guidance(itinerary blaise.Itinerary) []guidanceEvent {
+	transit := make([]int, 0, len(itinerary.Legs))
+	for i, leg := range itinerary.Legs {
+		if leg.IsTransit() {
+			transit = append(transit, i)
+		}
+	}
+
+	events := make([]guidanceEvent, 0, len(transit)+1)
+	for n, i := range transit {
+		at := getReadyAt(itinerary.Legs[i])
+		if n+1 < len(transit) {
+			events = append(events, guidanceEvent{kind: guidanceTransfer, at: at, leg: i, next: transit[n+1]})
+		} else {
+			events = append(events, guidanceEvent{kind: guidanceAlight, at: at, leg: i})
+		}
+	}
+	if len(itinerary.Legs) > 0 {
+		events = append(events, guidanceEvent{kind: guidanceArrival, at: itinerary.ArrivalTime})
+	}
+	return events
+}
+
+// getReadyAt is when to tell the traveller to get off the leg soon: as the
+// vehicle leaves the stop before theirs, so the next stop is the one. Without
+// stops it is guidanceLead before arriving.
+func getReadyAt(leg blaise.Leg) blaise.Time {
+	stops := leg.Stops
+	if n := len(stops); n > 0 && stops[n-1].Location.ID == leg.To.ID {
+		stops = stops[:n-1]
+	}
+	if n := len(stops); n > 0 {
+		at := stops[n-1].DepartureTime
+		if at == 0 {
+			at = stops[n-1].ArrivalTime
+		}
+		if at >= leg.DepartureTime && at < leg.ArrivalTime {
+			return at
+		}
+	}
+	if leg.ArrivalTime > leg.DepartureTime+guidanceLead {
+		return leg.ArrivalTime - guidanceLead
+	}
+	return leg.ArrivalTime
+}
+
+func legName(leg blaise.Leg) string {
+	var sb strings.Builder
+	if leg.ShortName != nil {
+		fmt.Fprintf(&sb, "**%s %s**", leg.Mode, *leg.ShortName)
+	} else {
+		fmt.Fprintf(&sb, "**%s**", leg.Mode)
+	}
+	if leg.HeadSign != nil {
+		fmt.Fprintf(&sb, " towards **%s**", *leg.HeadSign)
+	}
+	return sb.String()
+}
+
+func (e guidanceEvent) message(tripName string, itinerary blaise.Itinerary) string {
+	switch e.kind {
+	case guidanceTransfer:
+		leg, next := itinerary.Legs[e.leg], itinerary.Legs[e.next]
+		return fmt.Sprintf("🔄 **Transfer Soon:** **%s** get off at **%s** and change to %s, departing **%s**",
+			tripName, leg.To.Name, legName(next), next.DepartureTime.ToHMSString())
+	case guidanceAlight:
+		leg := itinerary.Legs[e.leg]
+		return fmt.Sprintf("🛑 **Stop Coming Up:** **%s** get off at **%s** in **%d** min",
+			tripName, leg.To.Name, max((leg.ArrivalTime-e.at)/60, 1))
+	default:
+		return fmt.Sprintf("🏁 **Arrived:** **%s** you have arrived at **%s**", tripName, itinerary.To.Name)
+	}
+}
+
+func (e guidanceEvent) requestKind() RequestKind {
+	switch e.kind {
+	case guidanceTransfer:
+		return RequestTransfer
+	case guidanceAlight:
+		return RequestAlight
+	default:
+		return RequestArrival
+	}
+}
@@ -0,0 +1,141 @@
+package state
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vincbro/pascal/blaise"
+)
+
+func stop(id string, at blaise.Time) blaise.Stop {
+	return blaise.Stop{Location: blaise.Location{ID: id}, ArrivalTime: at, DepartureTime: at}
+}
+
+func TestGetReadyAt(t *testing.T) {
+	tests := []struct {
+		name  string
+		stops []blaise.Stop
+		want  blaise.Time
+	}{
+		{
+			name:  "leaving the stop before",
+			stops: []blaise.Stop{stop("a", 1000), stop("b", 1200), stop("c", 1500), stop("d", 1800)},
+			want:  1500,
+		},
+		{
+			// Some legs list the stops in between without the one to get off at
+			name:  "stops without the last",
+			stops: []blaise.Stop{stop("a", 1000), stop("b", 1200), stop("c", 1500)},
+			want:  1500,
+		},
+		{
+			name:  "one stop ride",
+			stops: []blaise.Stop{stop("a", 1000), stop("d", 1800)},
+			want:  1000,
+		},
+		{
+			name:  "stop only has an arrival",
+			stops: []blaise.Stop{stop("a", 1000), {Location: blaise.Location{ID: "c"}, ArrivalTime: 1400}, stop("d", 1800)},
+			want:  1400,
+		},
+		{
+			name: "no stops",
+			want: 1800 - guidanceLead,
+		},
+		{
+			// Stops that don't fit the leg are ignored
+			name:  "stop times outside the leg",
+			stops: []blaise.Stop{stop("a", 900), stop("d", 1800)},
+			want:  1800 - guidanceLead,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			leg := blaise.Leg{
+				From:          blaise.Location{ID: "a"},
+				To:            blaise.Location{ID: "d"},
+				DepartureTime: 1000,
+				ArrivalTime:   1800,
+				Stops:         tt.stops,
+			}
+			if got := getReadyAt(leg); got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	short := blaise.Leg{To: blaise.Location{ID: "d"}, DepartureTime: 1000, ArrivalTime: 1030}
+	if got := getReadyAt(short); got != 1030 {
+		t.Errorf("got %d on a leg shorter than the lead, want its arrival", got)
+	}
+}
+
+// TestGuidanceOnTheClock rides the fixture commute, with and without the
+// stops of its legs, and checks when and what the traveller is told.
+func TestGuidanceOnTheClock(t *testing.T) {
+	tests := []struct {
+		name      string
+		keepStops bool
+		want      []sentRequest
+		messages  []string
+	}{
+		{
+			name:      "with stops",
+			keepStops: true,
+			want:      []sentRequest{{"08:02", RequestTransfer}, {"08:09", RequestAlight}, {"08:13", RequestArrival}},
+			messages: []string{
+				"get off at **Stockholm Centralstation** and change to **Subway 14**",
+				"get off at **Tekniska högskolan** in **4** min",
+				"you have arrived at **Tekniska högskolan**",
+			},
+		},
+		{
+			name: "without stops",
+			want: []sentRequest{{"08:03", RequestTransfer}, {"08:12", RequestAlight}, {"08:13", RequestArrival}},
+			messages: []string{
+				"get off at **Stockholm Centralstation** and change to **Subway 14**",
+				"get off at **Tekniska högskolan** in **1** min",
+				"you have arrived at **Tekniska högskolan**",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc := mustLocation(t, stockholm)
+			env := newTestEnv(t, time.Date(2026, 10, 19, 7, 50, 0, 0, loc))
+			trip := env.addCommute(t, "commute")
+			if !tt.keepStops {
+				for i := range trip.ExpectedItinerary.Legs {
+					trip.ExpectedItinerary.Legs[i].Stops = nil
+				}
+				if err := env.state.DB.UpdateTrip(trip); err != nil {
+					t.Fatalf("failed to update trip: %v", err)
+				}
+			}
+			env.runScheduler(t)
+
+			got := make([]Request, 0)
+			at := make([]sentRequest, 0)
+			for range 30 {
+				for _, request := range env.step(time.Minute) {
+					if request.Kind == RequestTransfer || request.Kind == RequestAlight || request.Kind == RequestArrival {
+						got = append(got, request)
+						at = append(at, sentRequest{at: env.clock.Now().Format("15:04"), kind: request.Kind})
+					}
+				}
+			}
+			if len(at) != len(tt.want) {
+				t.Fatalf("got guidance %v, want %v", at, tt.want)
+			}
+			for i := range tt.want {
+				if at[i] != tt.want[i] {
+					t.Errorf("guidance %d is %v, want %v", i, at[i], tt.want[i])
+				}
+				if !strings.Contains(got[i].Message, tt.messages[i]) {
+					t.Errorf("guidance %d is %q, want it to say %q", i, got[i].Message, tt.messages[i])
+				}
+			}
+		})
+	}
+}
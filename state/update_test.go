@@ -0,0 +1,167 @@
+package state
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vincbro/pascal/blaise"
+)
+
+// quickPolicies makes the client retry without waiting and give up after
+// timeout, so failure paths don't slow the tests down.
+func (e *testEnv) quickPolicies(timeout time.Duration) {
+	for call, policy := range e.state.BClient.Policies {
+		policy.Timeout = timeout
+		policy.BaseDelay = time.Millisecond
+		policy.MaxDelay = time.Millisecond
+		e.state.BClient.Policies[call] = policy
+	}
+}
+
+// staleCommute saves the fixture commute with an itinerary that has since
+// moved five minutes earlier.
+func (e *testEnv) staleCommute(t *testing.T) {
+	t.Helper()
+	trip := e.addCommute(t, "commute", 15*60)
+	trip.ExpectedItinerary.DepartureTime -= 5 * 60
+	if err := e.state.DB.UpdateTrip(trip); err != nil {
+		t.Fatalf("failed to update trip: %v", err)
+	}
+}
+
+func (e *testEnv) departure(t *testing.T) blaise.Time {
+	t.Helper()
+	trip, err := e.state.DB.GetTrip(e.user.ID, "commute")
+	if err != nil {
+		t.Fatalf("failed to get trip: %v", err)
+	}
+	return trip.ExpectedItinerary.DepartureTime
+}
+
+// eventually polls cond until it holds, for work done on real time like
+// calls to the fake server.
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestUpdateAllTrips(t *testing.T) {
+	env := newTestEnv(t, time.Date(2026, 10, 19, 6, 0, 0, 0, mustLocation(t, stockholm)))
+	env.staleCommute(t)
+
+	if err := env.state.UpdateAllTrips(); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	if departure := env.departure(t); departure != 28800 {
+		t.Errorf("trip departs at %d after the update, want 28800", departure)
+	}
+	select {
+	case cmd := <-env.state.schedule:
+		if cmd.trip == nil || cmd.trip.ID != "commute" {
+			t.Errorf("got schedule command %+v, want the updated commute", cmd)
+		}
+	default:
+		t.Error("the updated trip was not rescheduled")
+	}
+}
+
+func TestUpdateAllTripsFailures(t *testing.T) {
+	tests := []struct {
+		name        string
+		setup       func(e *testEnv)
+		unavailable bool
+	}{
+		{"loading", func(e *testEnv) { e.server.SetLoading(true) }, true},
+		{"server errors", func(e *testEnv) { e.server.Fail("/routing", 500, 502, 503) }, true},
+		{"latency", func(e *testEnv) { e.server.SetLatency(200 * time.Millisecond) }, true},
+		// A body that doesn't decode is not worth retrying the whole update for
+		{"malformed", func(e *testEnv) { e.server.SetMalformed("/routing", true) }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newTestEnv(t, time.Date(2026, 10, 19, 6, 0, 0, 0, mustLocation(t, stockholm)))
+			env.staleCommute(t)
+			env.quickPolicies(20 * time.Millisecond)
+			tt.setup(env)
+
+			err := env.state.UpdateAllTrips()
+			if got := errors.Is(err, blaise.ErrUnavailable); got != tt.unavailable {
+				t.Errorf("got error %v, unavailable %v, want %v", err, got, tt.unavailable)
+			}
+			if departure := env.departure(t); departure != 28500 {
+				t.Errorf("trip departs at %d after a failed update, want it left at 28500", departure)
+			}
+			if len(env.state.schedule) != 0 {
+				t.Error("a trip that failed to update was rescheduled")
+			}
+		})
+	}
+}
+
+func TestUpdateAllTripsCircuitOpen(t *testing.T) {
+	env := newTestEnv(t, time.Date(2026, 10, 19, 6, 0, 0, 0, mustLocation(t, stockholm)))
+	env.staleCommute(t)
+	env.state.BClient.Breaker = blaise.NewCircuitBreaker(1, time.Hour)
+	env.state.BClient.Breaker.Record(blaise.ErrUnavailable)
+
+	if err := env.state.UpdateAllTrips(); !errors.Is(err, blaise.ErrCircuitOpen) {
+		t.Errorf("got error %v with the circuit open, want ErrCircuitOpen", err)
+	}
+}
+
+func TestNightlyRefreshAndDispatch(t *testing.T) {
+	env := newTestEnv(t, time.Date(2026, 10, 19, 5, 30, 0, 0, mustLocation(t, stockholm)))
+	env.staleCommute(t)
+	env.server.SetAge(30 * 3600)
+	env.state.gtfsUrl = "https://example.com/gtfs.zip"
+
+	received := make(chan Request, 8)
+	env.state.AddHandler(func(s *State, request Request) error {
+		received <- request
+		return nil
+	})
+	env.state.Start()
+	t.Cleanup(env.state.Stop)
+	// The scheduler and the hourly check
+	env.clock.BlockUntil(schedulerTimers + 1)
+
+	// 06:30 is inside the refresh window and the data is a day old
+	env.clock.Advance(time.Hour)
+	eventually(t, "the refresh", func() bool { return len(env.server.Refreshes()) == 1 })
+	eventually(t, "the trip update", func() bool { return env.departure(t) == 28800 })
+	if refreshes := env.server.Refreshes(); refreshes[0] != "https://example.com/gtfs.zip" {
+		t.Errorf("refreshed from %q", refreshes[0])
+	}
+
+	eventually(t, "the reschedule", func() bool { return len(env.state.schedule) == 0 })
+
+	// The first alert follows the updated departure, the stale one at 07:40
+	// is gone and fresh data is left alone by the 07:30 check
+	env.clock.BlockUntil(schedulerTimers + 1)
+	env.clock.Advance(time.Hour + 5*time.Minute)
+	env.clock.BlockUntil(schedulerTimers + 1)
+	select {
+	case request := <-received:
+		t.Fatalf("got request %+v before 07:45", request)
+	default:
+	}
+	env.clock.Advance(10 * time.Minute)
+	select {
+	case request := <-received:
+		if request.Kind != RequestDepartSoon || request.TripID != "commute" || request.ServiceDate != "2026-10-19" {
+			t.Errorf("got request %+v, want the 07:45 depart soon alert", request)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the 07:45 alert was never dispatched")
+	}
+	if refreshes := env.server.Refreshes(); len(refreshes) != 1 {
+		t.Errorf("refreshed %d times, want once", len(refreshes))
+	}
+}